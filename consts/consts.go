@@ -8,6 +8,13 @@ const (
 	HudTimeout = 2.5
 	Delta      = 0.022
 
+	// The physics engine is stepped TickRate times per second independently of the
+	// frame rate, each step advancing the simulation by Delta, so the simulation runs
+	// in real time. On slow frames at most MaxCatchUpSteps steps are run, the rest of
+	// the elapsed time being dropped.
+	TickRate        = 1 / Delta
+	MaxCatchUpSteps = 5
+
 	WindowSizeX = 1280
 	WindowSizeY = 820
 
@@ -18,6 +25,16 @@ const (
 	DefaultFocusArea = 50
 	MinFocusArea     = 30
 	MaxFocusArea     = 120
+
+	// The strength and the radius of the force fields placed with the keyboard.
+	ForceStrength = 1500
+	ForceRadius   = 150
+
+	// The minimum distance kept between the cloth particles when the self collision is enabled.
+	SelfCollisionDist = 4
+
+	// The number of steps between the automatic compactions of the cloth, dropping its torn off parts.
+	CompactInterval = 60
 )
 
 var (
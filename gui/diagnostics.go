@@ -0,0 +1,160 @@
+package gui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+
+	"github.com/esimov/cloth-physics/physics"
+)
+
+// graphSamples is the number of simulation steps shown on the diagnostics graphs.
+const graphSamples = 240
+
+var graphColor = color.NRGBA{R: 0xd9, G: 0x03, B: 0x68, A: 0xcc}
+
+// series is a time series of the values measured on each step, stored in a ring buffer.
+type series struct {
+	title  string
+	format string
+	values [graphSamples]float64
+	next   int
+	count  int
+}
+
+// add appends a new value, overwriting the oldest one when the buffer is full.
+func (s *series) add(v float64) {
+	s.values[s.next] = v
+	s.next = (s.next + 1) % graphSamples
+	s.count = min(s.count+1, graphSamples)
+}
+
+// at returns the i-th value, counted from the oldest one.
+func (s *series) at(i int) float64 {
+	return s.values[(s.next-s.count+i+graphSamples)%graphSamples]
+}
+
+// last returns the most recent value.
+func (s *series) last() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.at(s.count - 1)
+}
+
+// Diagnostics is the HUD overlay showing the cloth diagnostics measured on the recent
+// simulation steps, with a small graph for each of the time series.
+type Diagnostics struct {
+	last   physics.Diagnostics
+	series []*series
+}
+
+// NewDiagnostics creates a new empty diagnostics overlay.
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{
+		series: []*series{
+			{title: "Kinetic energy", format: "%.0f"},
+			{title: "Potential energy", format: "%.0f"},
+			{title: "Max strain", format: "%.3f"},
+			{title: "Mean strain", format: "%.4f"},
+			{title: "Tears per second", format: "%.0f"},
+		},
+	}
+}
+
+// Add records the diagnostics of a simulation step.
+func (d *Diagnostics) Add(diag physics.Diagnostics) {
+	d.last = diag
+	for i, v := range []float64{
+		diag.KineticEnergy,
+		diag.PotentialEnergy,
+		diag.MaxStrain,
+		diag.MeanStrain,
+		diag.TearRate,
+	} {
+		d.series[i].add(v)
+	}
+}
+
+// Reset drops the recorded diagnostics.
+func (d *Diagnostics) Reset() {
+	for _, s := range d.series {
+		s.next, s.count = 0, 0
+	}
+	d.last = physics.Diagnostics{}
+}
+
+// Layout draws the counters of the last step followed by the graph of each time series.
+func (d *Diagnostics) Layout(gtx layout.Context, th *material.Theme, col color.NRGBA) layout.Dimensions {
+	label := func(txt string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			m := material.Label(th, unit.Sp(12), txt)
+			m.Color = col
+			return m.Layout(gtx)
+		})
+	}
+	children := []layout.FlexChild{
+		label(fmt.Sprintf("Particles: %d  Pinned: %d  Constraints: %d", d.last.Particles, d.last.Pinned, d.last.Constraints)),
+	}
+	for _, s := range d.series {
+		children = append(children,
+			label(fmt.Sprintf("%s: "+s.format, s.title, s.last())),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return drawGraph(gtx, s)
+				})
+			}),
+		)
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// drawGraph draws the series as a line scaled between its minimum and maximum value.
+func drawGraph(gtx layout.Context, s *series) layout.Dimensions {
+	size := image.Pt(gtx.Dp(unit.Dp(180)), gtx.Dp(unit.Dp(28)))
+	paint.FillShape(gtx.Ops, color.NRGBA{A: 20}, clip.Rect{Max: size}.Op())
+
+	if s.count < 2 {
+		return layout.Dimensions{Size: size}
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := 0; i < s.count; i++ {
+		lo, hi = math.Min(lo, s.at(i)), math.Max(hi, s.at(i))
+	}
+	// A flat series is drawn in the middle of the graph.
+	scale := 0.0
+	if hi > lo {
+		scale = 1 / (hi - lo)
+	}
+	point := func(i int) f32.Point {
+		y := 0.5
+		if scale > 0 {
+			y = (s.at(i) - lo) * scale
+		}
+		return f32.Pt(
+			float32(i)*float32(size.X)/float32(graphSamples-1),
+			float32(size.Y)*(1-float32(y)),
+		)
+	}
+
+	var path clip.Path
+	path.Begin(gtx.Ops)
+	path.MoveTo(point(0))
+	for i := 1; i < s.count; i++ {
+		path.LineTo(point(i))
+	}
+	paint.FillShape(gtx.Ops, graphColor, clip.Stroke{
+		Path:  path.End(),
+		Width: float32(gtx.Dp(1)),
+	}.Op())
+
+	return layout.Dimensions{Size: size}
+}
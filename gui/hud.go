@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/color"
 	"math"
+	"runtime"
 	"time"
 
 	"gioui.org/f32"
@@ -20,6 +21,7 @@ import (
 	"gioui.org/widget/material"
 	"github.com/esimov/cloth-physics/consts"
 	"github.com/esimov/cloth-physics/easing"
+	"github.com/esimov/cloth-physics/physics"
 )
 
 type (
@@ -34,9 +36,38 @@ const (
 	HudSliderGravityForce
 	HudSliderStiffness
 	HudSliderFriction
-	HudSliderTearDistance
+	HudSliderDragRadius
+	HudSliderIterations
+	HudSliderSubsteps
+	HudSliderShearStiffness
+	HudSliderBendStiffness
+	HudSliderCompliance
+	HudSliderTearStrain
+	HudSliderWindStrength
+	HudSliderWindDirection
+	HudSliderGustFrequency
+	HudSliderGustStrength
+	HudSliderTurbulence
 )
 
+// pinPatterns are the pin patterns selectable from the HUD.
+var pinPatterns = []physics.PinPattern{
+	physics.PinEveryNth,
+	physics.PinTopRow,
+	physics.PinCorners,
+	physics.PinLeftEdge,
+}
+
+// integrators are the integrators selectable from the HUD.
+var integrators = []physics.Integrator{
+	physics.PositionVerlet{},
+	physics.TimeCorrectedVerlet{},
+	physics.SemiImplicitEuler{},
+}
+
+// slidersPerColumn is the maximum number of sliders laid out in a single HUD column.
+const slidersPerColumn = 5
+
 type Hud struct {
 	Tag           struct{}
 	Sliders       map[HudSliderType]*slider
@@ -46,6 +77,12 @@ type Hud struct {
 	WinOffsetX    float64 // stores the X offset on window horizontal resize
 	WinOffsetY    float64 // stores the Y offset on window vertical resize
 	Debug         widget.Bool
+	WindPerTri    widget.Bool
+	SelfCollide   widget.Bool
+	Parallel      widget.Bool
+	XPBD          widget.Bool
+	PinPattern    widget.Enum
+	Integrator    widget.Enum
 	CloseBtn      int
 	BtnSize       int
 	IsActive      bool
@@ -56,6 +93,7 @@ type Hud struct {
 	ctrlBtn   easing.Easing
 	reset     widget.Clickable
 	list      layout.List
+	columns   []layout.List
 	activator gesture.Click
 	closer    gesture.Click
 	controls  gesture.Hover
@@ -63,11 +101,12 @@ type Hud struct {
 }
 
 type slider struct {
-	Widget *widget.Float
-	Title  string
-	Value  float32
-	Min    float32
-	Max    float32
+	Widget  *widget.Float
+	Title   string
+	Value   float32
+	Min     float32
+	Max     float32
+	Integer bool // the slider value is rounded to the nearest integer
 }
 
 // NewHud creates a new HUD used to interactively change the default settings via sliders and checkboxes.
@@ -88,7 +127,18 @@ func NewHud() *Hud {
 		{Title: "Gravity", Min: 100, Value: 250, Max: 500},
 		{Title: "Cloth friction", Min: 10, Value: 30, Max: 50},
 		{Title: "Cloth stiffness", Min: 0.95, Value: 0.98, Max: 0.99},
-		{Title: "Tear distance", Min: 5, Value: 15, Max: 50},
+		{Title: "Drag radius", Min: 5, Value: 15, Max: 50},
+		{Title: "Solver iterations", Min: 1, Value: 1, Max: 20, Integer: true},
+		{Title: "Substeps", Min: 1, Value: 1, Max: 10, Integer: true},
+		{Title: "Shear stiffness", Min: 0, Value: 0, Max: 1},
+		{Title: "Bend stiffness", Min: 0, Value: 0, Max: 1},
+		{Title: "XPBD compliance (1e-4)", Min: 0, Value: 1, Max: 10},
+		{Title: "Tear strain", Min: 2, Value: 20, Max: 40},
+		{Title: "Wind strength", Min: 0, Value: 0, Max: 1000},
+		{Title: "Wind direction", Min: 0, Value: 0, Max: 360, Integer: true},
+		{Title: "Gust frequency", Min: 0, Value: 0.5, Max: 5},
+		{Title: "Gust strength", Min: 0, Value: 0.5, Max: 1},
+		{Title: "Turbulence", Min: 0, Value: 0.3, Max: 1},
 	}
 
 	for idx, slider := range sliders {
@@ -103,6 +153,8 @@ func NewHud() *Hud {
 		{"Click & hold": "Increase cloth tearing force"},
 		{"Scroll Up/Down": "Increase/decrease cloth tearing area"},
 		{"CTRL+click": "Pin the particle at mouse position"},
+		{"A / R / V": "Place an attractor / repulsor / vortex"},
+		{"X": "Remove the force fields"},
 	}
 
 	for idx, cmd := range commands {
@@ -114,6 +166,8 @@ func NewHud() *Hud {
 
 	hud.Debug = widget.Bool{}
 	hud.Debug.Value = false
+	hud.PinPattern.Value = physics.PinEveryNth.String()
+	hud.Integrator.Value = fmt.Sprint(integrators[0])
 	hud.ctrlPanel = slide
 	hud.ctrlBtn = hover
 
@@ -128,12 +182,108 @@ func (h *Hud) addSlider(index HudSliderType, s slider) {
 	h.Sliders[index] = &s
 }
 
+// Params returns the simulation parameters set on the HUD sliders.
+func (h *Hud) Params() physics.Params {
+	return physics.Params{
+		DragForce:    float64(h.Sliders[HudSliderDragForce].Widget.Value),
+		MaxDragForce: float64(h.Sliders[HudSliderDragForce].Max),
+		Gravity:      float64(h.Sliders[HudSliderGravityForce].Widget.Value),
+		Stiffness:    float64(h.Sliders[HudSliderStiffness].Widget.Value),
+		Friction:     float64(h.Sliders[HudSliderFriction].Widget.Value),
+		DragRadius:   float64(h.Sliders[HudSliderDragRadius].Widget.Value),
+		TearStrain:   float64(h.Sliders[HudSliderTearStrain].Widget.Value),
+		Iterations:   h.Sliders[HudSliderIterations].intValue(),
+		Substeps:     h.Sliders[HudSliderSubsteps].intValue(),
+
+		ShearStiffness: float64(h.Sliders[HudSliderShearStiffness].Widget.Value),
+		BendStiffness:  float64(h.Sliders[HudSliderBendStiffness].Widget.Value),
+		SelfCollision:  h.selfCollision(),
+		Workers:        h.workers(),
+		Solver:         h.solver(),
+		Compliance:     float64(h.Sliders[HudSliderCompliance].Widget.Value) * complianceScale,
+	}
+}
+
+// complianceScale is the unit of the compliance slider, which would be too small to read otherwise.
+const complianceScale = 1e-4
+
+// solver returns the constraint solver selected on the HUD.
+func (h *Hud) solver() physics.Solver {
+	if h.XPBD.Value {
+		return physics.SolverXPBD
+	}
+	return physics.SolverRelaxation
+}
+
+// workers returns the number of goroutines solving the constraints, one for each CPU with the parallel solver enabled.
+func (h *Hud) workers() int {
+	if h.Parallel.Value {
+		return runtime.NumCPU()
+	}
+	return 1
+}
+
+// selfCollision returns the minimum distance between the cloth particles, or zero if the self collision is disabled.
+func (h *Hud) selfCollision() float64 {
+	if h.SelfCollide.Value {
+		return consts.SelfCollisionDist
+	}
+	return 0
+}
+
+// Wind returns the wind set on the HUD. The wind direction is set in degrees on the slider.
+func (h *Hud) Wind() physics.Wind {
+	mode := physics.WindPerParticle
+	if h.WindPerTri.Value {
+		mode = physics.WindPerTriangle
+	}
+	return physics.Wind{
+		Direction:     float64(h.Sliders[HudSliderWindDirection].Widget.Value) * math.Pi / 180,
+		Strength:      float64(h.Sliders[HudSliderWindStrength].Widget.Value),
+		GustFrequency: float64(h.Sliders[HudSliderGustFrequency].Widget.Value),
+		GustStrength:  float64(h.Sliders[HudSliderGustStrength].Widget.Value),
+		Turbulence:    float64(h.Sliders[HudSliderTurbulence].Widget.Value),
+		Mode:          mode,
+	}
+}
+
+// SelectedPinPattern returns the pin pattern selected on the HUD.
+func (h *Hud) SelectedPinPattern() physics.PinPattern {
+	for _, p := range pinPatterns {
+		if p.String() == h.PinPattern.Value {
+			return p
+		}
+	}
+	return physics.PinEveryNth
+}
+
+// SelectedIntegrator returns the integrator selected on the HUD.
+func (h *Hud) SelectedIntegrator() physics.Integrator {
+	for _, in := range integrators {
+		if fmt.Sprint(in) == h.Integrator.Value {
+			return in
+		}
+	}
+	return integrators[0]
+}
+
+// intValue returns the slider value rounded to the nearest integer.
+func (s *slider) intValue() int {
+	return int(math.Round(float64(s.Widget.Value)))
+}
+
 // ShowControlPanel is responsible for showing or hiding the HUD control elements.
 func (h *Hud) ShowControlPanel(gtx layout.Context, th *material.Theme, isActive bool) {
 	if h.reset.Pressed() {
 		for _, s := range h.Sliders {
 			s.Widget.Value = s.Value
 		}
+		h.PinPattern.Value = physics.PinEveryNth.String()
+		h.Integrator.Value = fmt.Sprint(integrators[0])
+		h.WindPerTri.Value = false
+		h.SelfCollide.Value = false
+		h.Parallel.Value = false
+		h.XPBD.Value = false
 	}
 
 	progress := h.ctrlPanel.Update(gtx, isActive)
@@ -218,39 +368,54 @@ func (h *Hud) ShowControlPanel(gtx layout.Context, th *material.Theme, isActive
 
 	pointer.CursorPointer.Add(gtx.Ops)
 
+	// The sliders are distributed into columns, the panel being as high as the highest column.
+	h.PanelHeight = 0
+	var children []layout.FlexChild
+	for col := 0; col*slidersPerColumn < len(h.Sliders); col++ {
+		if col == len(h.columns) {
+			h.columns = append(h.columns, layout.List{Axis: layout.Vertical})
+		}
+		children = append(children, layout.Rigid(func(gtx C) D {
+			return h.layoutSliders(gtx, th, col)
+		}))
+	}
+
 	/* Draw HUD Contents */
 	layout.Flex{
 		Spacing: layout.SpaceEnd,
-	}.Layout(gtx,
+	}.Layout(gtx, append(children,
 		layout.Rigid(func(gtx C) D {
-			gtx.Constraints.Min.X = h.PanelWidth / 3
-			gtx.Constraints.Max.X = gtx.Constraints.Min.X
-			layout := layout.UniformInset(unit.Dp(20)).Layout(gtx, func(gtx C) D {
-				return h.list.Layout(gtx, len(h.Sliders),
-					func(gtx C, index int) D {
-						sliderType := HudSliderType(index)
-						if slider, ok := h.Sliders[sliderType]; ok {
-							var precisionFmt string
-							if slider.Widget.Value > 1 {
-								precisionFmt = "%s: %.0f"
-							} else {
-								precisionFmt = "%s: %.2f"
-							}
-							return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-								layout.Rigid(material.Body1(th, fmt.Sprintf(precisionFmt, slider.Title, slider.Widget.Value)).Layout),
-								layout.Flexed(1, material.Slider(th, slider.Widget, slider.Min, slider.Max).Layout),
-							)
-						}
-						return D{}
+			dims := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, func(gtx C) D {
+						return h.layoutPinPatterns(gtx, th)
 					})
-			})
-			h.PanelHeight = layout.Size.Y + h.CloseBtn
-			return layout
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, func(gtx C) D {
+						return h.layoutIntegrators(gtx, th)
+					})
+				}),
+			)
+			h.PanelHeight = max(h.PanelHeight, dims.Size.Y+h.CloseBtn)
+			return dims
 		}),
 		layout.Rigid(func(gtx C) D {
-			return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			dims := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.CheckBox(th, &h.Debug, "Show diagnostics").Layout)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.CheckBox(th, &h.WindPerTri, "Wind per triangle").Layout)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.CheckBox(th, &h.SelfCollide, "Self collision").Layout)
+				}),
 				layout.Rigid(func(gtx C) D {
-					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.CheckBox(th, &h.Debug, "Show Frame Rates").Layout)
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.CheckBox(th, &h.Parallel, "Parallel solver").Layout)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.CheckBox(th, &h.XPBD, "XPBD solver").Layout)
 				}),
 				layout.Rigid(func(gtx C) D {
 					btnTheme := material.NewTheme()
@@ -258,6 +423,8 @@ func (h *Hud) ShowControlPanel(gtx layout.Context, th *material.Theme, isActive
 					return layout.UniformInset(unit.Dp(10)).Layout(gtx, material.Button(btnTheme, &h.reset, "Reset").Layout)
 				}),
 			)
+			h.PanelHeight = max(h.PanelHeight, dims.Size.Y+h.CloseBtn)
+			return dims
 		}),
 
 		layout.Flexed(1, func(gtx C) D {
@@ -274,7 +441,64 @@ func (h *Hud) ShowControlPanel(gtx layout.Context, th *material.Theme, isActive
 				}),
 			)
 		}),
-	)
+	)...)
+}
+
+// layoutPinPatterns lays out the radio buttons used for selecting the pin pattern.
+func (h *Hud) layoutPinPatterns(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	children := []layout.FlexChild{
+		layout.Rigid(material.Body1(th, "Pin pattern").Layout),
+	}
+	for _, p := range pinPatterns {
+		children = append(children, layout.Rigid(
+			material.RadioButton(th, &h.PinPattern, p.String(), p.String()).Layout,
+		))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// layoutIntegrators lays out the radio buttons used for selecting the integrator.
+func (h *Hud) layoutIntegrators(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	children := []layout.FlexChild{
+		layout.Rigid(material.Body1(th, "Integrator").Layout),
+	}
+	for _, in := range integrators {
+		name := fmt.Sprint(in)
+		children = append(children, layout.Rigid(
+			material.RadioButton(th, &h.Integrator, name, name).Layout,
+		))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// layoutSliders lays out the sliders of a single HUD column.
+func (h *Hud) layoutSliders(gtx layout.Context, th *material.Theme, col int) layout.Dimensions {
+	first := col * slidersPerColumn
+	count := min(slidersPerColumn, len(h.Sliders)-first)
+
+	gtx.Constraints.Min.X = h.PanelWidth / 5
+	gtx.Constraints.Max.X = gtx.Constraints.Min.X
+	dims := layout.UniformInset(unit.Dp(20)).Layout(gtx, func(gtx C) D {
+		return h.columns[col].Layout(gtx, count,
+			func(gtx C, index int) D {
+				sliderType := HudSliderType(first + index)
+				if slider, ok := h.Sliders[sliderType]; ok {
+					var precisionFmt string
+					if slider.Integer || slider.Widget.Value > 1 {
+						precisionFmt = "%s: %.0f"
+					} else {
+						precisionFmt = "%s: %.2f"
+					}
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(material.Body1(th, fmt.Sprintf(precisionFmt, slider.Title, slider.Widget.Value)).Layout),
+						layout.Flexed(1, material.Slider(th, slider.Widget, slider.Min, slider.Max).Layout),
+					)
+				}
+				return D{}
+			})
+	})
+	h.PanelHeight = max(h.PanelHeight, dims.Size.Y+h.CloseBtn)
+	return dims
 }
 
 // DrawCtrlBtn draws the button which activates the main HUD area with the sliders.
@@ -25,6 +25,7 @@ import (
 	"github.com/esimov/cloth-physics/consts"
 	"github.com/esimov/cloth-physics/gui"
 	"github.com/esimov/cloth-physics/physics"
+	"github.com/esimov/cloth-physics/render"
 	"github.com/loov/hrtime"
 )
 
@@ -33,6 +34,7 @@ const Version = "v1.0.3"
 const (
 	hudTimeout = consts.HudTimeout
 	delta      = consts.Delta
+	tickRate   = consts.TickRate
 
 	windowSizeX = consts.WindowSizeX
 	windowSizeY = consts.WindowSizeY
@@ -47,6 +49,9 @@ var (
 
 	// App related variables
 	hud    *gui.Hud
+	diag   *gui.Diagnostics
+	world  *physics.World
+	clock  *physics.Accumulator
 	cloth  *physics.Cloth
 	mouse  *physics.Mouse
 	clothW int
@@ -56,11 +61,14 @@ var (
 
 	// Gio Ops related variables
 	ops          op.Ops
-	initTime     time.Time
-	deltaTime    time.Duration
-	mouseScrollY unit.Dp
+	lastFrame    time.Time
+	mouseScrollY float64
 	mouseDrag    bool
 
+	// scene related variables
+	scene     string
+	obstacles []physics.Obstacle
+
 	// pprof related variables
 	profile string
 	file    *os.File
@@ -69,8 +77,21 @@ var (
 
 func main() {
 	flag.StringVar(&profile, "debug-cpuprofile", "", "write CPU profile to this file")
+	flag.StringVar(&scene, "scene", "", "load the obstacles from this JSON scene file")
 	flag.Parse()
 
+	if scene != "" {
+		f, err := os.Open(scene)
+		if err != nil {
+			log.Fatal(err)
+		}
+		obstacles, err = physics.LoadScene(f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	if profile != "" {
 		file, err = os.Create(profile)
 		if err != nil {
@@ -79,11 +100,14 @@ func main() {
 	}
 
 	hud = gui.NewHud()
+	diag = gui.NewDiagnostics()
 
 	mouse = &physics.Mouse{}
 	mouse.SetScrollY(consts.DefaultFocusArea)
 	mouse.SetMaxScrollY(consts.MaxFocusArea)
 
+	clock = physics.NewAccumulator(tickRate, consts.MaxCatchUpSteps)
+
 	go func() {
 		w := app.NewWindow(
 			app.Title("Gio - 2D Cloth Simulation"),
@@ -156,6 +180,7 @@ func run(w *app.Window) error {
 						return 2 * clothSpacing
 					}()
 					cloth = physics.NewCloth(clothW, clothH, clothSpacing, defaultColor)
+					cloth.CompactInterval = consts.CompactInterval
 
 					width := gtx.Constraints.Max.X
 					height := gtx.Constraints.Max.Y
@@ -163,19 +188,21 @@ func run(w *app.Window) error {
 					startX := int(unit.Dp(width-clothW) / 2)
 					startY := int(unit.Dp(height) * 0.2)
 
-					cloth.Init(startX, startY, hud)
+					cloth.Init(startX, startY)
+
+					world = physics.NewWorld(float64(width), float64(height))
+					world.Mouse = mouse
+					world.Add(cloth)
+					for _, o := range obstacles {
+						world.AddObstacle(o)
+					}
 				}
 
 				key.InputOp{
 					Tag:  &keyTag,
-					Keys: key.NameEscape + "|" + key.NameCtrl + "|" + key.NameAlt + "|" + key.NameSpace + "|" + key.NameF1,
+					Keys: key.NameEscape + "|" + key.NameCtrl + "|" + key.NameAlt + "|" + key.NameSpace + "|" + key.NameF1 + "|A|R|V|X",
 				}.Add(gtx.Ops)
 
-				if mouse.GetLeftButton() {
-					deltaTime = time.Since(initTime)
-					mouse.SetForce(deltaTime.Seconds() * 5)
-				}
-
 				for _, ev := range gtx.Queue.Events(&keyTag) {
 					if e, ok := ev.(key.Event); ok {
 						if e.State == key.Press {
@@ -190,10 +217,19 @@ func run(w *app.Window) error {
 								cloth.Width = clothW
 								cloth.Height = clothH
 
-								cloth.Reset(startX, startY, hud)
+								cloth.Reset(startX, startY)
+								diag.Reset()
 							case key.NameF1:
 								hud.ShowHelpPanel = !hud.ShowHelpPanel
 								hud.IsActive = false
+							case "A":
+								world.AddForce(physics.NewAttractor(mouse.GetPosition(), consts.ForceStrength, consts.ForceRadius))
+							case "R":
+								world.AddForce(physics.NewRepulsor(mouse.GetPosition(), consts.ForceStrength, consts.ForceRadius))
+							case "V":
+								world.AddForce(physics.NewVortex(mouse.GetPosition(), consts.ForceStrength, consts.ForceRadius))
+							case "X":
+								world.ClearForces()
 							}
 						}
 						if e.Name == key.NameEscape {
@@ -214,7 +250,7 @@ func run(w *app.Window) error {
 				}
 
 				if e.Size.X != windowWidth || e.Size.Y != windowHeight {
-					cloth.Init(windowWidth, windowHeight, hud)
+					cloth.Init(windowWidth, windowHeight)
 
 					windowWidth = e.Size.X
 					windowHeight = e.Size.Y
@@ -266,7 +302,7 @@ func run(w *app.Window) error {
 								key.FocusOp{Tag: keyTag}.Add(gtx.Ops)
 								switch ev.Type {
 								case pointer.Scroll:
-									mouseScrollY += unit.Dp(ev.Scroll.Y)
+									mouseScrollY += float64(ev.Scroll.Y)
 									if mouseScrollY < consts.MinFocusArea {
 										mouseScrollY = consts.MinFocusArea
 									} else if mouseScrollY > mouse.GetMaxScrollY() {
@@ -274,14 +310,12 @@ func run(w *app.Window) error {
 									}
 									mouse.SetScrollY(mouseScrollY)
 								case pointer.Move:
-									pos := mouse.GetCurrentPosition(ev)
-									mouse.UpdatePosition(float64(pos.X), float64(pos.Y))
+									mouse.UpdatePosition(float64(ev.Position.X), float64(ev.Position.Y))
 								case pointer.Press:
 									if ev.Modifiers == key.ModCtrl {
 										mouse.SetCtrlDown(true)
 									}
 									mouse.SetLeftButton()
-									initTime = time.Now()
 									hud.ShowHelpPanel = false
 								case pointer.Release:
 									mouseDrag = false
@@ -297,17 +331,48 @@ func run(w *app.Window) error {
 								switch ev.Buttons {
 								case pointer.ButtonPrimary:
 									mouse.SetLeftButton()
-									pos := mouse.GetCurrentPosition(ev)
-									mouse.UpdatePosition(float64(pos.X), float64(pos.Y))
+									mouse.UpdatePosition(float64(ev.Position.X), float64(ev.Position.Y))
 									mouse.SetDragging(mouseDrag)
 								case pointer.ButtonSecondary:
 									mouse.SetRightButton()
-									pos := mouse.GetCurrentPosition(ev)
-									mouse.UpdatePosition(float64(pos.X), float64(pos.Y))
+									mouse.UpdatePosition(float64(ev.Position.X), float64(ev.Position.Y))
+								}
+							}
+						}
+						// Recalculate the pinned particles position when the window is resized.
+						// We need to do this only for the pinned particles, because the rest
+						// of the particles will just adjust themselves automatically.
+						cloth.MovePinned(hud.WinOffsetX, hud.WinOffsetY)
+
+						// Pin the cloth again when a new pin pattern is selected on the HUD.
+						if p := hud.SelectedPinPattern(); p != cloth.PinPattern {
+							cloth.PinPattern = p
+							cloth.Repin()
+						}
+
+						world.Resize(float64(gtx.Constraints.Max.X), float64(gtx.Constraints.Max.Y))
+						if err := world.SetParams(hud.Params()); err != nil {
+							log.Println(err)
+						}
+						world.Wind = hud.Wind()
+						world.SetIntegrator(hud.SelectedIntegrator())
+
+						// Run as many fixed steps as needed to catch up with the time elapsed since
+						// the last frame, then render the state interpolated between the last two steps.
+						if !lastFrame.IsZero() {
+							steps := clock.Advance(e.Now.Sub(lastFrame).Seconds())
+							for i := 0; i < steps; i++ {
+								world.Step(delta)
+								if hud.Debug.Value {
+									diag.Add(cloth.Diagnostics())
 								}
 							}
 						}
-						cloth.Update(gtx, mouse, hud, delta)
+						lastFrame = e.Now
+
+						render.Obstacles(gtx, world.Obstacles())
+						render.Cloth(gtx, cloth, mouse, clock.Alpha())
+						render.Forces(gtx, world.Forces())
 						return layout.Dimensions{}
 					}),
 
@@ -317,9 +382,16 @@ func run(w *app.Window) error {
 								layout.Stacked(func(gtx layout.Context) layout.Dimensions {
 									op.Offset(image.Pt(10, 10)).Add(gtx.Ops)
 									return layout.E.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-										m := material.Label(th, unit.Sp(15), hrtime.Since(start).String())
-										m.Color = defaultColor
-										return m.Layout(gtx)
+										return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+											layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+												m := material.Label(th, unit.Sp(15), hrtime.Since(start).String())
+												m.Color = defaultColor
+												return m.Layout(gtx)
+											}),
+											layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+												return diag.Layout(gtx, th, defaultColor)
+											}),
+										)
 									})
 								}),
 							)
@@ -0,0 +1,47 @@
+package physics
+
+// Accumulator splits the real time elapsed between two rendered frames into fixed
+// size steps, which makes the simulation independent of the frame rate.
+type Accumulator struct {
+	Interval float64 // the real time in seconds covered by a single step
+	MaxSteps int     // the maximum number of catch-up steps run on a single frame
+
+	elapsed float64
+}
+
+// NewAccumulator creates a new accumulator running rate steps per second.
+func NewAccumulator(rate float64, maxSteps int) *Accumulator {
+	return &Accumulator{
+		Interval: 1 / rate,
+		MaxSteps: maxSteps,
+	}
+}
+
+// Advance adds the time elapsed since the last frame to the accumulator and returns
+// the number of fixed steps needed to catch up with it. On a slow machine the number
+// of steps is capped to MaxSteps and the time which could not be simulated is dropped,
+// otherwise each frame would take longer than the previous one.
+func (a *Accumulator) Advance(elapsed float64) int {
+	a.elapsed += elapsed
+
+	steps := int(a.elapsed / a.Interval)
+	if a.MaxSteps > 0 && steps > a.MaxSteps {
+		steps = a.MaxSteps
+		a.elapsed = 0
+		return steps
+	}
+	a.elapsed -= float64(steps) * a.Interval
+
+	return steps
+}
+
+// Alpha returns the fraction of a step left in the accumulator.
+// It's used for interpolating the rendered state between the last two steps.
+func (a *Accumulator) Alpha() float64 {
+	return a.elapsed / a.Interval
+}
+
+// Reset drops the accumulated time.
+func (a *Accumulator) Reset() {
+	a.elapsed = 0
+}
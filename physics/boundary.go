@@ -0,0 +1,117 @@
+package physics
+
+// BoundaryMode defines what happens with the particles reaching an edge of the world.
+type BoundaryMode int
+
+const (
+	// BoundaryClamp stops the particles at the edge, cancelling their velocity towards it.
+	BoundaryClamp BoundaryMode = iota
+	// BoundaryBounce reflects the particles from the edge, keeping the restitution
+	// fraction of their velocity towards it.
+	BoundaryBounce
+	// BoundaryFriction stops the particles at the edge like the clamp mode,
+	// but also slows down the particles sliding along the edge.
+	BoundaryFriction
+	// BoundaryWrap moves the particles leaving the world to the opposite edge.
+	// The constraints of the wrapped particles are stretched over the whole
+	// world, which means that they will likely break.
+	BoundaryWrap
+	// BoundaryOpen lets the particles leave the world. The particles outside
+	// of the world are deactivated and their constraints are released.
+	BoundaryOpen
+)
+
+// Edge identifies one of the edges of the world.
+type Edge int
+
+const (
+	EdgeLeft Edge = iota
+	EdgeTop
+	EdgeRight
+	EdgeBottom
+)
+
+// Boundary describes the behaviour of an edge of the world.
+type Boundary struct {
+	Mode        BoundaryMode
+	Restitution float64 // the fraction of the velocity kept by the bouncing particles, in the [0, 1] range
+	Friction    float64 // the fraction of the sliding velocity lost on contact, in the [0, 1] range
+}
+
+// confine applies the boundary behaviour of the edges on the particle i. It returns
+// false if the particle has left the world through an open edge.
+func (s *particleStore) confine(i int, bounds Bounds) bool {
+	if !s.free(i) {
+		return true
+	}
+
+	if p := s.pos[i]; p.X >= bounds.Width {
+		if !s.cross(i, EdgeRight, bounds) {
+			return false
+		}
+	} else if p.X < 0 {
+		if !s.cross(i, EdgeLeft, bounds) {
+			return false
+		}
+	}
+
+	if p := s.pos[i]; p.Y > bounds.Height {
+		if !s.cross(i, EdgeBottom, bounds) {
+			return false
+		}
+	} else if p.Y < 0 {
+		if !s.cross(i, EdgeTop, bounds) {
+			return false
+		}
+	}
+	return true
+}
+
+// cross resolves the particle i crossing the e edge. It returns false if the edge is open.
+func (s *particleStore) cross(i int, e Edge, bounds Bounds) bool {
+	b := bounds.Edges[e]
+
+	// The coordinates perpendicular to the edge are the pos, prev and last ones,
+	// while the tpos and tprev coordinates are the ones along the edge.
+	pos, prev, last, tpos, tprev := &s.pos[i].X, &s.prev[i].X, &s.last[i].X, &s.pos[i].Y, &s.prev[i].Y
+	size := bounds.Width
+	if e == EdgeTop || e == EdgeBottom {
+		pos, prev, last, tpos, tprev = &s.pos[i].Y, &s.prev[i].Y, &s.last[i].Y, &s.pos[i].X, &s.prev[i].X
+		size = bounds.Height
+	}
+	// The limit is the coordinate of the edge, and the shift moves the particle to the opposite edge.
+	limit, shift := 0.0, size
+	if e == EdgeRight || e == EdgeBottom {
+		limit, shift = size, -size
+	}
+
+	switch b.Mode {
+	case BoundaryBounce:
+		v := *pos - *prev
+		*pos = limit
+		*prev = limit + v*b.Restitution
+		*tprev = *tpos - (*tpos-*tprev)*(1-b.Friction)
+	case BoundaryFriction:
+		*pos = limit
+		*prev = limit
+		*tprev = *tpos - (*tpos-*tprev)*(1-b.Friction)
+	case BoundaryWrap:
+		// The last position is moved too, otherwise the
+		// particle would be interpolated across the world.
+		*pos += shift
+		*prev += shift
+		*last += shift
+	case BoundaryOpen:
+		s.flags[i] &^= particleActive
+		return false
+	default:
+		*pos = limit
+		*prev = *pos
+	}
+	return true
+}
+
+// release removes the constraints attached to the deactivated particles.
+func (c *Cloth) release() {
+	c.reclaimed.Constraints += c.filter(c.isActive)
+}
@@ -1,29 +1,44 @@
 package physics
 
 import (
+	"fmt"
 	"image/color"
 	"math"
-
-	"gioui.org/f32"
-	"gioui.org/layout"
-	"gioui.org/op/clip"
-	"gioui.org/op/paint"
-
-	"github.com/esimov/cloth-physics/gui"
-	"github.com/esimov/cloth-physics/utils"
 )
 
-const lineWidth = 0.6
-
 type Cloth struct {
-	constraints   []*constraint
-	particles     []*particle
+	constraints   constraintStore
+	particles     particleStore
+	triangles     [][3]int
+	triangleCount []int // the number of triangles each particle belongs to
 	Width         int
 	Height        int
 	spacing       int
-	friction      float64
+	cols, rows    int
 	color         color.NRGBA
 	isInitialized bool
+	hash          spatialHash // the particle lookup used by the self collision
+	grid          []int       // the index of the particle from each grid position or mesh vertex, -1 for the dropped ones
+	steps         int         // the number of simulation steps since the cloth was created
+	lastDt        float64     // the duration of the last substep, used by the time-corrected integrators
+	tears         tearCounter
+	reclaimed     Reclaimed
+	pieces        pieceTracker
+	world         *World            // the world the cloth was added to, receiving its events
+	colors        colorSets         // the independent constraint sets of the parallel solver
+	mesh          *mesh             // the triangle mesh of the cloth, nil for the grid cloths
+	latticeSets   [2]latticeSet     // the shear and the bend sticks
+	sticks        map[[2]int]*stick // the lattice sticks by the particles they connect
+	contacts      []int             // the obstacle each particle has touched during the substep, -1 for none
+
+	PinPattern PinPattern // the pattern of the particles pinned on initialization
+	PinEvery   int        // the distance between the pinned particles of the PinEveryNth pattern
+	PinIndices []int      // the index of the pinned particles of the PinCustom pattern
+
+	// CompactInterval is the number of simulation steps between two automatic compactions,
+	// zero disabling them. The compaction moves the particles to new indices, so it should
+	// be enabled only when the caller doesn't hold on to the particle indices.
+	CompactInterval int
 }
 
 // NewCloth creates a new cloth which dimension is calculated based on
@@ -39,130 +54,436 @@ func NewCloth(width, height, spacing int, col color.NRGBA) *Cloth {
 
 // Init initializes the cloth where the `posX` and `posY`
 // are the {x, y} position of the cloth's the top-left side.
-func (c *Cloth) Init(posX, posY int, hud *gui.Hud) {
-	clothX := c.Width / c.spacing
-	clothY := c.Height / c.spacing
-
+func (c *Cloth) Init(posX, posY int) {
 	// Skip the cloth initialization when the window is resized.
 	if c.isInitialized {
 		return
 	}
+	if c.mesh != nil {
+		c.initMesh(posX, posY)
+		return
+	}
+	clothX := c.Width / c.spacing
+	clothY := c.Height / c.spacing
+
+	c.cols, c.rows = clothX+1, clothY+1
+
+	// at returns the index of the particle from the {x, y} grid position.
+	at := func(x, y int) int {
+		return x + y*(clothX+1)
+	}
+	spacing := float64(c.spacing)
+
+	// The sticks ending at each grid position, connecting it to the particles before it. They are
+	// kept for linking the shear and bend sticks to the structural ones they span.
+	n := c.cols * c.rows
+	horizontal, vertical := make([]*stick, n), make([]*stick, n)
+	leftShear, rightShear := make([]*stick, n), make([]*stick, n)
+	horizontalBend, verticalBend := make([]*stick, n), make([]*stick, n)
 
 	for y := 0; y <= clothY; y++ {
 		for x := 0; x <= clothX; x++ {
 			px := posX + x*c.spacing
 			py := posY + y*c.spacing
 
-			particle := NewParticle(float64(px), float64(py), hud, c.color)
-			particle.friction = c.friction
-
 			// Connect the particles with sticks but skip the particles from the first column and row.
 			// We connect the particles from the second row and column onward to the particles before.
+			// The diagonal neighbours are connected by shear constraints and the particles
+			// two rows or columns apart by bend constraints.
+			idx := at(x, y)
 			if y != 0 {
-				top := c.particles[x+(y-1)*(clothX+1)]
-				constraint := NewConstraint(top, particle, float64(c.spacing), c.color)
-				c.constraints = append(c.constraints, constraint)
+				vertical[idx] = c.connect(at(x, y-1), idx, spacing, structural)
 			}
 			if x != 0 {
-				left := c.particles[len(c.particles)-1]
-				constraint := NewConstraint(left, particle, float64(c.spacing), c.color)
-				c.constraints = append(c.constraints, constraint)
+				horizontal[idx] = c.connect(at(x-1, y), idx, spacing, structural)
 			}
-
-			pinX := x % (clothX / 10)
-			if y == 0 && pinX == 0 {
-				particle.pinX = true
+			if y != 0 && x != 0 {
+				leftShear[idx] = c.connect(at(x-1, y-1), idx, spacing*math.Sqrt2, shear)
+			}
+			if y != 0 && x != clothX {
+				rightShear[idx] = c.connect(at(x+1, y-1), idx, spacing*math.Sqrt2, shear)
+			}
+			if y > 1 {
+				verticalBend[idx] = c.connect(at(x, y-2), idx, 2*spacing, bend)
+			}
+			if x > 1 {
+				horizontalBend[idx] = c.connect(at(x-2, y), idx, 2*spacing, bend)
+			}
+			// Each grid cell is divided into two triangles.
+			if x != 0 && y != 0 {
+				c.AddTriangle(at(x-1, y-1), at(x, y-1), at(x-1, y))
+				c.AddTriangle(at(x, y-1), idx, at(x-1, y))
 			}
 
-			c.particles = append(c.particles, particle)
+			i := c.particles.add(float64(px), float64(py))
+			c.particles.set(i, particlePinned, c.isPinned(x, y))
+			c.grid = append(c.grid, i)
+		}
+	}
+
+	// The shear sticks span the two sides of their grid cell, while
+	// the bend sticks the two structural sticks they run along.
+	for y := 0; y <= clothY; y++ {
+		for x := 0; x <= clothX; x++ {
+			idx := at(x, y)
+			if s := leftShear[idx]; s != nil {
+				s.addPath(horizontal[at(x, y-1)], vertical[idx], at(x, y-1))
+				s.addPath(vertical[at(x-1, y)], horizontal[idx], at(x-1, y))
+			}
+			if s := rightShear[idx]; s != nil {
+				s.addPath(horizontal[at(x+1, y-1)], vertical[idx], at(x, y-1))
+				s.addPath(vertical[at(x+1, y)], horizontal[at(x+1, y)], at(x+1, y))
+			}
+			if s := verticalBend[idx]; s != nil {
+				s.addPath(vertical[at(x, y-1)], vertical[idx], at(x, y-1))
+			}
+			if s := horizontalBend[idx]; s != nil {
+				s.addPath(horizontal[at(x-1, y)], horizontal[idx], at(x-1, y))
+			}
 		}
 	}
 	c.isInitialized = true
 }
 
-// Update updates the cloth particles invoked on each frame event of the Gio internal window calls.
-// The cloth contraints are solved by using the Verlet integration formulas.
-func (cloth *Cloth) Update(gtx layout.Context, mouse *Mouse, hud *gui.Hud, dt float64) {
-	dragForce := float32(mouse.GetForce() * 0.1)
-	clothColor := color.NRGBA{R: 0x55, A: 0xff}
+// connect connects two particles with a new stick of the provided kind and returns the stick.
+// The shear and bend sticks are added to the constraints only once their stiffness is nonzero.
+func (c *Cloth) connect(p1, p2 int, length float64, kind constraintKind) *stick {
+	if c.sticks == nil {
+		c.sticks = make(map[[2]int]*stick)
+	}
+	stick := newStick(p1, p2, length, c.color)
+	stick.kind = kind
+	c.sticks[stickKey(p1, p2)] = stick
+	if kind == structural {
+		c.constraints.add(stick)
+	} else {
+		set := c.lattice(kind)
+		set.sticks = append(set.sticks, stick)
+		stick.removed = true
+	}
+	return stick
+}
 
-	// Convert the RGB color to HSL based on the applied force over the mouse focus area.
-	col := utils.LinearFromSRGB(clothColor).HSLA().Lighten(dragForce).RGBA().SRGB()
+// AddParticle adds a new particle at the {x, y} position and returns its index.
+// Together with AddConstraint it can be used to build custom bodies.
+func (c *Cloth) AddParticle(x, y float64) int {
+	return c.particles.add(x, y)
+}
 
-	for _, p := range cloth.particles {
-		p.Update(gtx, mouse, hud, dt)
+// AddTriangle adds a new triangle defined by the index of its vertices.
+// The triangles are used for applying the wind on the cloth surface.
+func (c *Cloth) AddTriangle(a, b, d int) {
+	c.triangles = append(c.triangles, [3]int{a, b, d})
+	for _, i := range [3]int{a, b, d} {
+		if i >= len(c.triangleCount) {
+			c.triangleCount = append(c.triangleCount, make([]int, i-len(c.triangleCount)+1)...)
+		}
+		c.triangleCount[i]++
 	}
+}
+
+// AddConstraint adds a new constraint acting on the cloth particles.
+// The constraint should be a comparable value, like a pointer.
+func (c *Cloth) AddConstraint(constraint Constraint) {
+	c.constraints.add(constraint)
+}
 
-	for _, c := range cloth.constraints {
-		if c.p1.isActive && c.p2.isActive {
-			c.Update(gtx, cloth, mouse)
+// Constraints returns the constraints of the cloth, without the shear and bend sticks disabled
+// by their zero stiffness. The returned slice is owned by the cloth and it's valid only until
+// the next simulation step.
+func (c *Cloth) Constraints() []Constraint {
+	c.constraints.compact()
+	return c.constraints.items
+}
+
+// Particles returns the indexed accessor of the cloth particles.
+func (c *Cloth) Particles() Particles {
+	return Particles{s: &c.particles}
+}
+
+// SetBreakStrain sets the breaking strain of the lattice stick connecting the particles a and b,
+// overriding the default threshold. It reports whether such a stick exists.
+func (c *Cloth) SetBreakStrain(a, b int, strain float64) bool {
+	s := c.findStick(a, b)
+	if s != nil {
+		s.breakStrain = strain
+	}
+	return s != nil
+}
+
+// SetCompliance sets the compliance used by the XPBD solver for the lattice stick connecting
+// the particles a and b, overriding the default one. A negative compliance restores the default.
+// It reports whether such a stick exists.
+func (c *Cloth) SetCompliance(a, b int, compliance float64) bool {
+	s := c.findStick(a, b)
+	if s != nil {
+		s.compliance = compliance
+	}
+	return s != nil
+}
+
+// SetMass sets the mass of the particle i. The mass should be a positive value.
+func (c *Cloth) SetMass(i int, mass float64) error {
+	if mass <= 0 {
+		return fmt.Errorf("invalid particle mass: %g", mass)
+	}
+	c.particles.setMass(i, mass)
+	return nil
+}
+
+// SetRegionMass sets the mass of the particles found inside the rectangle defined
+// by the min and max corners, and returns the number of the updated particles.
+// This can be used to simulate weighted hems, heavy corners or attached weights.
+func (c *Cloth) SetRegionMass(min, max Vec2, mass float64) (int, error) {
+	if mass <= 0 {
+		return 0, fmt.Errorf("invalid particle mass: %g", mass)
+	}
+	var count int
+	for i, p := range c.particles.pos {
+		if p.X >= min.X && p.X <= max.X && p.Y >= min.Y && p.Y <= max.Y {
+			c.particles.setMass(i, mass)
+			count++
 		}
 	}
+	return count, nil
+}
 
-	var path clip.Path
-	path.Begin(gtx.Ops)
+// RemoveConstraint removes a specific constraint from the cloth. The removal takes constant time
+// and it's safe to call it during the simulation step, the constraint being skipped from then on.
+// It reports whether the constraint was found.
+func (c *Cloth) RemoveConstraint(constraint Constraint) bool {
+	i, ok := c.constraints.find(constraint)
+	if ok {
+		c.removeAt(i)
+	}
+	return ok
+}
+
+// removeAt removes the constraint found at position i. Removing a structural stick of the lattice
+// also removes the shear and bend sticks spanning it, which are left without an intact path.
+func (c *Cloth) removeAt(i int) {
+	s, _ := c.constraints.items[i].(*stick)
+	c.constraints.removeAt(i)
+	if s == nil {
+		return
+	}
+	c.forget(s)
+	for _, d := range s.dependents {
+		if !d.intact(&c.particles) && c.constraints.remove(d) {
+			c.forget(d)
+		}
+	}
+}
 
-	// For performance reasons we draw the sticks as a single clip path
-	// instead of multiple clips paths. The performance improvement is
-	// considerable compared to draw each clip path separately.
-	for _, c := range cloth.constraints {
-		if c.p1.isActive && c.p2.isActive {
-			a := f32.Pt(float32(c.p1.x), float32(c.p1.y))
-			b := f32.Pt(float32(c.p2.x), float32(c.p2.y))
-			addSegment(&path, a, b, lineWidth)
+// isActive reports if all the particles the constraint acts on are active.
+func (c *Cloth) isActive(constraint Constraint) bool {
+	// The lattice sticks are by far the most common constraints,
+	// so they are checked directly, without the interface call.
+	if s, ok := constraint.(*stick); ok {
+		return c.particles.active(s.idx[0]) && c.particles.active(s.idx[1]) && s.intact(&c.particles)
+	}
+	for _, i := range constraint.Indices() {
+		if !c.particles.active(i) {
+			return false
 		}
 	}
-	// We are using `clip.Outline` instead of `clip.Stroke`, because the performance gains
-	// are much better, but we need to draw the full outline of the stroke.
-	paint.FillShape(gtx.Ops, cloth.color, clip.Outline{
-		Path: path.End(),
-	}.Op())
+	return true
+}
+
+// update updates the cloth particles and resolves the constraints between them.
+// The particles are advanced in time by the integrator of the world.
+// The step is divided into substeps, and on each substep the constraints are relaxed
+// multiple times, trading CPU time for a stiffer cloth.
+func (cloth *Cloth) update(w *World, mouse *Mouse, dt float64) {
+	// The parameters are read only once per step, so they
+	// cannot change while the particles are updated.
+	params, bounds := w.params, w.Bounds
+	substeps := max(params.Substeps, 1)
+	h := dt / float64(substeps)
+
+	cloth.syncLattice()
+	copy(cloth.particles.last, cloth.particles.pos)
 
-	// Draw the mouse focus area in a separate clip path.
-	// The color used for highlighting the selected area
-	// should be different than the cloth's default color.
-	path.Begin(gtx.Ops)
+	ctx := &Context{
+		Particles: cloth.Particles(),
+		Params:    params,
+		Mouse:     mouse,
+		Dt:        h,
+	}
+
+	for s := 0; s < substeps; s++ {
+		if params.Solver == SolverXPBD {
+			cloth.resetLambdas()
+		}
+		cloth.applyWind(w.Wind, w.time+float64(s)*h)
+		cloth.applyForces(w.forces, w.time+float64(s)*h)
+		cloth.applyConstraintForces()
+
+		var escaped bool
+		ps, in := &cloth.particles, newIntegration(mouse, params, w.integrator, h, cloth.lastDt, substeps)
+		for i, flags := range ps.flags {
+			ps.update(i, in)
+			if !ps.confine(i, bounds) {
+				escaped = true
+			}
+			if flags&particleActive != 0 && !ps.active(i) {
+				cloth.pieces.invalidate()
+				cloth.emitParticle(EventParticleDeactivated, i)
+			}
+			if flags&particlePinned == 0 && ps.pinned(i) {
+				cloth.emitParticle(EventParticlePinned, i)
+			}
+		}
+		if escaped {
+			cloth.release()
+		}
+		if params.SelfCollision > 0 {
+			cloth.hash.build(&cloth.particles, params.SelfCollision)
+		}
+
+		var pool *workerPool
+		if params.Workers > 1 {
+			cloth.colors.build(cloth)
+			pool = w.workers(params.Workers)
+		}
+
+		for i := 0; i < max(params.Iterations, 1); i++ {
+			if pool != nil {
+				cloth.solveParallel(ctx, pool)
+			} else {
+				for _, c := range cloth.constraints.items {
+					if c != nil && cloth.isActive(c) {
+						c.Solve(ctx)
+					}
+				}
+			}
+			cloth.collide(w.obstacles)
+			if params.SelfCollision > 0 {
+				cloth.collideSelf(params.SelfCollision)
+			}
+		}
+		cloth.rub(w.obstacles)
+		ps.updateVelocities(h)
+		cloth.lastDt = h
+		cloth.tear(ctx)
+	}
+	// The torn constraints are only marked as removed during the step, and they are
+	// dropped all at once when enough of them have piled up after the steps.
+	cloth.constraints.tidy()
+	cloth.detectDetached()
+	cloth.tears.commit(w.time + dt)
+	cloth.steps++
+}
 
-	for _, c := range cloth.constraints {
-		if (c.p1.isActive && c.p1.highlighted) &&
-			(c.p2.isActive && c.p2.highlighted) {
+// tear removes the constraints which are stretched beyond their breaking strain.
+// The shear and bend sticks spanning a broken structural stick are removed together with it.
+func (cloth *Cloth) tear(ctx *Context) {
+	for i, c := range cloth.constraints.items {
+		if c != nil && cloth.isBroken(c, ctx) {
+			cloth.emit(Event{Type: EventConstraintBroken, Particles: append([]int(nil), c.Indices()...), Constraint: c})
+			cloth.tears.step++
+			cloth.removeAt(i)
+		}
+	}
+}
 
-			a := f32.Pt(float32(c.p1.x), float32(c.p1.y))
-			b := f32.Pt(float32(c.p2.x), float32(c.p2.y))
-			addSegment(&path, a, b, lineWidth)
+// filter removes the constraints for which keep returns false, and returns their number.
+func (cloth *Cloth) filter(keep func(c Constraint) bool) int {
+	var n int
+	for i, c := range cloth.constraints.items {
+		if c != nil && !keep(c) {
+			cloth.constraints.removeAt(i)
+			if s, ok := c.(*stick); ok {
+				cloth.forget(s)
+			}
+			n++
 		}
 	}
+	return n
+}
 
-	paint.FillShape(gtx.Ops, color.NRGBA{R: col.R, A: col.A}, clip.Outline{
-		Path: path.End(),
-	}.Op())
+// isBroken reports if the constraint is stretched beyond its breaking strain.
+func (cloth *Cloth) isBroken(c Constraint, ctx *Context) bool {
+	b, ok := c.(Breakable)
+	if !ok {
+		return false
+	}
+	threshold := b.BreakStrain()
+	if threshold == 0 {
+		threshold = ctx.Params.TearStrain
+	}
+	if threshold <= 0 || !cloth.isActive(c) {
+		return false
+	}
+	return b.Strain(ctx.Particles) > threshold
 }
 
-// Reset resets the cloth to the initial state.
-func (c *Cloth) Reset(startX, startY int, hud *gui.Hud) {
-	c.constraints = nil
-	c.particles = nil
-	c.isInitialized = false
+// Sticks calls fn for each constraint connecting two active particles, except the shear
+// and bend constraints of the cloth lattice. The stick ends are interpolated between the
+// last two steps by alpha, where 0 means the previous and 1 the current position.
+// The highlighted flag reports if both ends of the stick are inside the mouse focus area.
+func (cloth *Cloth) Sticks(alpha float64, fn func(a, b Vec2, highlighted bool)) {
+	for _, c := range cloth.constraints.items {
+		if c == nil {
+			continue
+		}
+		if s, ok := c.(*stick); ok && s.kind != structural {
+			continue
+		}
+		idx := c.Indices()
+		if len(idx) != 2 {
+			continue
+		}
+		ps, i1, i2 := &cloth.particles, idx[0], idx[1]
+		if ps.active(i1) && ps.active(i2) {
+			fn(ps.interpolate(i1, alpha), ps.interpolate(i2, alpha), ps.is(i1, particleHighlighted) && ps.is(i2, particleHighlighted))
+		}
+	}
+}
 
-	c.Init(startX, startY, hud)
+// MovePinned moves the pinned particles by the {dx, dy} offset.
+// This is used to keep the cloth at the same relative position on window resize.
+func (cloth *Cloth) MovePinned(dx, dy float64) {
+	if dx == 0 && dy == 0 {
+		return
+	}
+	offset := Vec(dx, dy)
+	ps := &cloth.particles
+	for i := range ps.pos {
+		if ps.pinned(i) {
+			ps.pos[i] = ps.pos[i].Add(offset)
+			ps.prev[i] = ps.prev[i].Add(offset)
+			ps.last[i] = ps.last[i].Add(offset)
+		}
+	}
 }
 
-func addSegment(p *clip.Path, a, b f32.Point, w float32) {
-	n := normal(a, b, w)
-	p.MoveTo(a.Add(n))
-	p.LineTo(b.Add(n))
-	p.LineTo(b.Sub(n))
-	p.LineTo(a.Sub(n))
-	p.Close()
+// Color returns the cloth's default color.
+func (cloth *Cloth) Color() color.NRGBA {
+	return cloth.color
 }
 
-// Calculate the scaled normal vector.
-func normal(a, b f32.Point, w float32) f32.Point {
-	dir := b.Sub(a)
-	dir.X, dir.Y = +dir.Y, -dir.X
-	d := math.Hypot(float64(dir.X), float64(dir.Y))
-	if math.Abs(d) < 1e-5 {
-		return f32.Point{}
+// params returns the parameters of the world the cloth was added to, or the default ones.
+func (c *Cloth) params() Params {
+	if c.world != nil {
+		return c.world.params
 	}
-	return dir.Mul(w / float32(d))
+	return DefaultParams()
+}
+
+// Reset resets the cloth to the initial state.
+func (c *Cloth) Reset(startX, startY int) {
+	c.constraints.reset()
+	c.particles.reset()
+	c.triangles = nil
+	c.triangleCount = nil
+	c.grid = nil
+	c.latticeSets = [2]latticeSet{}
+	c.sticks = nil
+	c.lastDt = 0
+	c.tears = tearCounter{}
+	c.isInitialized = false
+
+	c.Init(startX, startY)
+	c.emit(Event{Type: EventClothReset})
 }
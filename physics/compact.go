@@ -0,0 +1,92 @@
+package physics
+
+// Reclaimed holds the number of the items dropped from a cloth, by its compaction
+// or when its particles have left the world through an open boundary.
+type Reclaimed struct {
+	Particles   int
+	Constraints int
+	Triangles   int
+}
+
+// Compact drops the deactivated particles with the constraints and triangles referencing them,
+// and moves the remaining particles to new indices, invalidating the indices held by the caller.
+// It runs automatically when CompactInterval is set. The dropped items are added to Reclaimed.
+func (c *Cloth) Compact() Reclaimed {
+	r := Reclaimed{Constraints: c.filter(c.isActive)}
+	c.constraints.compact()
+
+	defer func() {
+		c.reclaimed.Particles += r.Particles
+		c.reclaimed.Constraints += r.Constraints
+		c.reclaimed.Triangles += r.Triangles
+	}()
+
+	// The particles cannot be moved if some constraint is not able to follow them.
+	for _, ct := range c.constraints.items {
+		if _, ok := ct.(Remapper); !ok {
+			return r
+		}
+	}
+
+	index := make([]int, c.particles.len())
+	var kept int
+	for i := range index {
+		if c.particles.active(i) {
+			index[i] = kept
+			kept++
+		} else {
+			index[i] = -1
+			r.Particles++
+		}
+	}
+	if r.Particles == 0 {
+		return r
+	}
+	c.particles.compact(index)
+
+	// The pieces are moved to the new indices too, so the pieces
+	// detached later are still compared with the previous ones.
+	if len(c.pieces.label) == len(index) {
+		label := c.pieces.label[:0]
+		for i, l := range c.pieces.label {
+			if index[i] >= 0 {
+				label = append(label, l)
+			}
+		}
+		c.pieces.label = label
+	}
+	c.pieces.invalidate()
+
+	for _, ct := range c.constraints.items {
+		ct.(Remapper).Remap(index)
+	}
+	c.remapLattice(index)
+	c.indexSticks()
+
+	triangles := c.triangles[:0]
+	c.triangleCount = make([]int, c.particles.len())
+	for _, tri := range c.triangles {
+		a, b, d := index[tri[0]], index[tri[1]], index[tri[2]]
+		if a < 0 || b < 0 || d < 0 {
+			r.Triangles++
+			continue
+		}
+		triangles = append(triangles, [3]int{a, b, d})
+		c.triangleCount[a]++
+		c.triangleCount[b]++
+		c.triangleCount[d]++
+	}
+	c.triangles = triangles
+
+	for i, idx := range c.grid {
+		if idx >= 0 {
+			c.grid[i] = index[idx]
+		}
+	}
+	return r
+}
+
+// Reclaimed returns the number of the items dropped from the cloth since it was created.
+func (c *Cloth) Reclaimed() Reclaimed {
+	return c.reclaimed
+}
@@ -0,0 +1,83 @@
+package physics
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCompactRemapsLatticePaths(t *testing.T) {
+	c := NewCloth(60, 60, 10, color.NRGBA{})
+	c.PinPattern = PinCorners
+	c.Init(0, 0)
+
+	w := NewWorld(400, 400)
+	p := DefaultParams()
+	p.ShearStiffness = 0.5
+	p.BendStiffness = 0.5
+	if err := w.SetParams(p); err != nil {
+		t.Fatal(err)
+	}
+	w.Add(c)
+	w.Step(0.022)
+
+	// The particles are dropped one by one, so the second compaction finds
+	// the lattice paths running through the particle dropped by the first one.
+	for _, cell := range [][2]int{{3, 3}, {2, 4}} {
+		i, ok := c.Index(cell[0], cell[1])
+		if !ok {
+			t.Fatalf("no particle at %v", cell)
+		}
+		c.particles.flags[i] &^= particleActive
+		dropped := c.particles.pos[i]
+
+		// The sticks are compared through the position of their particles,
+		// which is not changed by the compaction.
+		type ends struct{ a, mid [2]Vec2 }
+		want := make(map[*stick]ends)
+		position := func(j int) Vec2 {
+			if j < 0 {
+				return Vec2{}
+			}
+			return c.particles.pos[j]
+		}
+		for _, ct := range c.constraints.items {
+			if s, ok := ct.(*stick); ok && c.isActive(s) {
+				want[s] = ends{
+					a:   [2]Vec2{position(s.idx[0]), position(s.idx[1])},
+					mid: [2]Vec2{position(s.paths[0].mid), position(s.paths[1].mid)},
+				}
+			}
+		}
+
+		if r := c.Compact(); r.Particles != 1 {
+			t.Fatalf("got %d dropped particles, want 1", r.Particles)
+		}
+		if len(c.constraints.items) != len(want) {
+			t.Fatalf("got %d sticks, want %d", len(c.constraints.items), len(want))
+		}
+		for _, ct := range c.constraints.items {
+			s := ct.(*stick)
+			e, ok := want[s]
+			if !ok {
+				t.Fatalf("unexpected stick %v", s.idx)
+			}
+			if got := [2]Vec2{position(s.idx[0]), position(s.idx[1])}; got != e.a {
+				t.Errorf("stick %v: got the particles at %v, want %v", s.idx, got, e.a)
+			}
+			for k, path := range s.paths {
+				if path.a == nil {
+					continue
+				}
+				// The dropped mid particles can't be found anymore, the rest keep their position.
+				if e.mid[k] == dropped && path.mid != -1 {
+					t.Errorf("stick %v: path %d runs through the dropped particle", s.idx, k)
+				} else if e.mid[k] != dropped && position(path.mid) != e.mid[k] {
+					t.Errorf("stick %v: got the path %d through %v, want %v", s.idx, k, position(path.mid), e.mid[k])
+				}
+			}
+			if c.findStick(s.idx[0], s.idx[1]) != s {
+				t.Errorf("stick %v: not found by its particles", s.idx)
+			}
+		}
+	}
+}
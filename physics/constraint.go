@@ -3,62 +3,227 @@ package physics
 import (
 	"image/color"
 	"math"
+)
+
+// Constraint restricts the movement of one or more particles of a body.
+// The constraints are relaxed one by one, multiple times on each substep,
+// which means that the cloth and the custom bodies can freely mix them.
+type Constraint interface {
+	// Solve relaxes the constraint by moving the particles it acts on.
+	Solve(ctx *Context)
+	// Indices returns the index of the particles the constraint acts on.
+	Indices() []int
+}
+
+// Breakable is implemented by the constraints which can be torn apart. A constraint breaks
+// when its strain exceeds the breaking threshold, no matter what caused the stretching.
+type Breakable interface {
+	Constraint
+	// Strain returns the current elongation of the constraint relative to its rest length.
+	Strain(ps Particles) float64
+	// BreakStrain returns the strain at which the constraint breaks. A zero value means that
+	// the default threshold from the simulation parameters applies, while a negative value
+	// makes the constraint unbreakable.
+	BreakStrain() float64
+}
+
+// Remapper is implemented by the constraints which can follow their particles moved to
+// a new index, when the removed particles are dropped from the body. The index slice maps
+// the old particle indices to the new ones. The bodies with constraints not implementing
+// this interface never get their removed particles dropped.
+type Remapper interface {
+	Remap(index []int)
+}
 
-	"gioui.org/layout"
+// forceConstraint is implemented by the constraints acting as forces. They are applied
+// once per substep before the integration, instead of on each relaxation.
+type forceConstraint interface {
+	Constraint
+	// accelerate adds the acceleration caused by the constraint to the particles.
+	accelerate(ps Particles)
+}
+
+// Context holds the state the constraints are solved with.
+type Context struct {
+	Particles Particles
+	Params    Params
+	Mouse     *Mouse
+	Dt        float64 // the duration of the current substep
+}
+
+// constraintKind tells the role of a stick in the cloth lattice.
+type constraintKind int
+
+const (
+	// structural constraints connect the horizontal and vertical neighbours.
+	structural constraintKind = iota
+	// shear constraints connect the diagonal neighbours, preventing
+	// the grid cells from collapsing into parallelograms.
+	shear
+	// bend constraints connect every second particle on a row or column,
+	// resisting the folding and wrinkling of the cloth.
+	bend
 )
 
-type constraint struct {
-	p1, p2 *particle
-	length float64
-	color  color.NRGBA
+// stick is the constraint the cloth lattice is built of.
+type stick struct {
+	idx         [2]int
+	length      float64
+	breakStrain float64
+	compliance  float64 // the compliance used by the XPBD solver, negative for the default one
+	lambda      float64
+	color       color.NRGBA
+	kind        constraintKind
+	removed     bool // the stick is not in the constraint store, being torn or disabled
+	slot        int  // the position of the stick in the constraint store
+
+	// The shear and bend sticks span one or two paths of structural sticks, and they act only
+	// while at least one of the paths is intact. The structural sticks list the dependent ones.
+	paths      [2]latticePath
+	dependents []*stick
 }
 
-// NewConstraint creates a new constraint between two points/particles.
-// The constraint actually is a stick which connects two points.
-func NewConstraint(p1, p2 *particle, length float64, col color.NRGBA) *constraint {
-	return &constraint{
-		p1, p2, length, col,
+// latticePath is a path of two structural sticks meeting at the mid particle.
+type latticePath struct {
+	a, b *stick
+	mid  int // -1 once the particle is dropped by the compaction
+}
+
+// addPath adds the path running along the a and b structural sticks through the mid particle.
+// The missing sticks are ignored, which happens only at the edges of the lattice.
+func (c *stick) addPath(a, b *stick, mid int) {
+	if a == nil || b == nil {
+		return
+	}
+	for i := range c.paths {
+		if c.paths[i].a == nil {
+			c.paths[i] = latticePath{a: a, b: b, mid: mid}
+			a.dependents = append(a.dependents, c)
+			b.dependents = append(b.dependents, c)
+			return
+		}
 	}
 }
 
-// Update updates the stick between two points by resolving the constraints between them.
-func (c *constraint) Update(gtx layout.Context, cloth *Cloth, mouse *Mouse) {
-	dx := c.p1.x - c.p2.x
-	dy := c.p1.y - c.p2.y
-	dist := math.Sqrt(dx*dx + dy*dy)
+// intact reports if the stick has at least one intact path, which means that both sticks of the
+// path are in place and the particle they meet at is active. The sticks without a path are intact.
+func (c *stick) intact(ps *particleStore) bool {
+	if c.paths[0].a == nil {
+		return true
+	}
+	for _, p := range c.paths {
+		if p.a != nil && !p.a.removed && !p.b.removed && p.mid >= 0 && ps.active(p.mid) {
+			return true
+		}
+	}
+	return false
+}
 
-	if dist < c.length {
-		return
+// newStick creates a new stick between two particles.
+func newStick(p1, p2 int, length float64, col color.NRGBA) *stick {
+	return &stick{
+		idx: [2]int{p1, p2}, length: length, compliance: -1, color: col,
 	}
-	// Tear up the cloth under the mouse position if the applied force exceeds a certain threshold.
-	// The threshold is the distance between the two points.
-	if mouse.GetDragging() {
-		if dist > 150 {
-			c.removeConstraint(cloth)
+}
+
+func (c *stick) resetLambda() {
+	c.lambda = 0
+}
+
+// Indices implements the Constraint interface.
+func (c *stick) Indices() []int {
+	return c.idx[:]
+}
+
+// Remap implements the Remapper interface.
+func (c *stick) Remap(index []int) {
+	c.idx[0], c.idx[1] = index[c.idx[0]], index[c.idx[1]]
+	for i, p := range c.paths {
+		if p.a != nil && p.mid >= 0 {
+			c.paths[i].mid = index[p.mid]
 		}
 	}
+}
 
-	diff := (c.length - dist) / dist
-	mul := diff * 0.35 * (1 - c.length/dist)
+// Strain implements the Breakable interface.
+func (c *stick) Strain(ps Particles) float64 {
+	return strain(ps, c.idx[0], c.idx[1], c.length)
+}
 
-	offsetX, offsetY := dx*mul, dy*mul
+// BreakStrain implements the Breakable interface.
+func (c *stick) BreakStrain() float64 {
+	return c.breakStrain
+}
 
-	if !c.p1.pinX {
-		c.p1.x += offsetX
-		c.p1.y += offsetY
+// strain returns the elongation of the distance between the particles a and b relative to length.
+func strain(ps Particles, a, b int, length float64) float64 {
+	if length == 0 {
+		return 0
 	}
-	if !c.p2.pinX {
-		c.p2.x -= offsetX
-		c.p2.y -= offsetY
+	return (ps.Position(b).Sub(ps.Position(a)).Len() - length) / length
+}
+
+// stiffness returns the stiffness of the stick based on its kind.
+// The structural sticks are always enabled, the rest of them
+// are disabled when their stiffness is set to zero.
+func (c *stick) stiffness(params Params) float64 {
+	switch c.kind {
+	case shear:
+		return params.ShearStiffness
+	case bend:
+		return params.BendStiffness
+	default:
+		return 1
 	}
 }
 
-// removeConstraint removes a specific constraint (stick) from the collection, stored into a slice.
-func (c *constraint) removeConstraint(cloth *Cloth) {
-	for idx, constraint := range cloth.constraints {
-		if c == constraint {
-			cloth.constraints = append(cloth.constraints[:idx], cloth.constraints[idx+1:]...)
-			break
+// Solve updates the stick between two points by resolving the constraints between them.
+func (c *stick) Solve(ctx *Context) {
+	stiffness := c.stiffness(ctx.Params)
+	if stiffness == 0 {
+		return
+	}
+	if ctx.Params.Solver == SolverXPBD {
+		// The default compliance is scaled by the stiffness of the shear and
+		// bend sticks, so their stiffness is a fraction of the structural one.
+		compliance := c.compliance
+		if compliance < 0 {
+			compliance = ctx.Params.Compliance / stiffness
 		}
+		solveCompliant(ctx.Particles, c.idx[0], c.idx[1], c.length, compliance, &c.lambda, ctx.Dt, c.kind == structural)
+		return
 	}
+	ps, i1, i2 := ctx.Particles.s, c.idx[0], c.idx[1]
+	p1, p2 := ps.pos[i1], ps.pos[i2]
+
+	dx := p1.X - p2.X
+	dy := p1.Y - p2.Y
+	dist := math.Sqrt(dx*dx + dy*dy)
+
+	// The structural sticks are acting only when they are stretched, while the shear
+	// and bend constraints are also resisting the compression, otherwise they
+	// couldn't prevent the cloth from collapsing or folding.
+	if dist < c.length && c.kind == structural || dist == 0 {
+		return
+	}
+	diff := (c.length - dist) / dist
+
+	var mul float64
+	if c.kind == structural {
+		mul = diff * 0.35 * (1 - c.length/dist)
+	} else {
+		mul = diff * 0.5 * stiffness
+	}
+
+	// The correction is weighted by the inverse mass of the particles. With equal masses
+	// both particles are moved by the same offset, while the pinned ones are not moved.
+	w1, w2 := ps.weight(i1), ps.weight(i2)
+	if w1+w2 == 0 {
+		return
+	}
+	mul *= 2 / (w1 + w2)
+	offsetX, offsetY := dx*mul, dy*mul
+
+	ps.pos[i1] = Vec(p1.X+offsetX*w1, p1.Y+offsetY*w1)
+	ps.pos[i2] = Vec(p2.X-offsetX*w2, p2.Y-offsetY*w2)
 }
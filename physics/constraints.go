@@ -0,0 +1,271 @@
+package physics
+
+import "math"
+
+// DistanceConstraint keeps two particles at a fixed distance from each other,
+// resisting both the stretching and the compression.
+type DistanceConstraint struct {
+	A, B       int
+	Length     float64
+	Stiffness  float64 // the fraction of the error corrected on each relaxation, in the [0, 1] range
+	Compliance float64 // the inverse of the stiffness used by the XPBD solver, zero being rigid
+	BreakAt    float64 // the breaking strain, see the Breakable interface
+
+	lambda float64
+}
+
+// NewDistanceConstraint creates a new distance constraint between the particles a and b.
+func NewDistanceConstraint(a, b int, length, stiffness float64) *DistanceConstraint {
+	return &DistanceConstraint{A: a, B: b, Length: length, Stiffness: stiffness}
+}
+
+// Indices implements the Constraint interface.
+func (c *DistanceConstraint) Indices() []int {
+	return []int{c.A, c.B}
+}
+
+// Remap implements the Remapper interface.
+func (c *DistanceConstraint) Remap(index []int) {
+	c.A, c.B = index[c.A], index[c.B]
+}
+
+// Solve implements the Constraint interface.
+func (c *DistanceConstraint) Solve(ctx *Context) {
+	if ctx.Params.Solver == SolverXPBD {
+		solveCompliant(ctx.Particles, c.A, c.B, c.Length, c.Compliance, &c.lambda, ctx.Dt, false)
+		return
+	}
+	solveDistance(ctx.Particles, c.A, c.B, c.Length, c.Stiffness, false)
+}
+
+func (c *DistanceConstraint) resetLambda() {
+	c.lambda = 0
+}
+
+// Strain implements the Breakable interface.
+func (c *DistanceConstraint) Strain(ps Particles) float64 {
+	return strain(ps, c.A, c.B, c.Length)
+}
+
+// BreakStrain implements the Breakable interface.
+func (c *DistanceConstraint) BreakStrain() float64 {
+	return c.BreakAt
+}
+
+// RopeConstraint limits the distance between two particles to a maximum length.
+// The particles can freely move closer to each other, like the ends of a rope.
+type RopeConstraint struct {
+	A, B       int
+	MaxLength  float64
+	Stiffness  float64 // the fraction of the error corrected on each relaxation, in the [0, 1] range
+	Compliance float64 // the inverse of the stiffness used by the XPBD solver, zero being rigid
+	BreakAt    float64 // the breaking strain, see the Breakable interface
+
+	lambda float64
+}
+
+// NewRopeConstraint creates a new rope constraint between the particles a and b.
+func NewRopeConstraint(a, b int, maxLength, stiffness float64) *RopeConstraint {
+	return &RopeConstraint{A: a, B: b, MaxLength: maxLength, Stiffness: stiffness}
+}
+
+// Indices implements the Constraint interface.
+func (c *RopeConstraint) Indices() []int {
+	return []int{c.A, c.B}
+}
+
+// Remap implements the Remapper interface.
+func (c *RopeConstraint) Remap(index []int) {
+	c.A, c.B = index[c.A], index[c.B]
+}
+
+// Solve implements the Constraint interface.
+func (c *RopeConstraint) Solve(ctx *Context) {
+	if ctx.Params.Solver == SolverXPBD {
+		solveCompliant(ctx.Particles, c.A, c.B, c.MaxLength, c.Compliance, &c.lambda, ctx.Dt, true)
+		return
+	}
+	solveDistance(ctx.Particles, c.A, c.B, c.MaxLength, c.Stiffness, true)
+}
+
+func (c *RopeConstraint) resetLambda() {
+	c.lambda = 0
+}
+
+// Strain implements the Breakable interface.
+func (c *RopeConstraint) Strain(ps Particles) float64 {
+	return strain(ps, c.A, c.B, c.MaxLength)
+}
+
+// BreakStrain implements the Breakable interface.
+func (c *RopeConstraint) BreakStrain() float64 {
+	return c.BreakAt
+}
+
+// SpringConstraint connects two particles with a damped spring following the Hooke's law.
+// Unlike the distance constraint it's not corrected at once, but it accelerates the particles
+// proportionally to the spring elongation, while the damping reduces their relative velocity.
+// The spring force is applied once per substep before the integration, and not on each relaxation,
+// so it works the same way with both solvers, whatever the number of iterations is.
+type SpringConstraint struct {
+	A, B       int
+	RestLength float64
+	Stiffness  float64 // the spring constant
+	Damping    float64 // the damping coefficient
+	BreakAt    float64 // the breaking strain, see the Breakable interface
+}
+
+// NewSpringConstraint creates a new damped spring between the particles a and b.
+func NewSpringConstraint(a, b int, restLength, stiffness, damping float64) *SpringConstraint {
+	return &SpringConstraint{A: a, B: b, RestLength: restLength, Stiffness: stiffness, Damping: damping}
+}
+
+// Indices implements the Constraint interface.
+func (c *SpringConstraint) Indices() []int {
+	return []int{c.A, c.B}
+}
+
+// Remap implements the Remapper interface.
+func (c *SpringConstraint) Remap(index []int) {
+	c.A, c.B = index[c.A], index[c.B]
+}
+
+// Solve implements the Constraint interface. The spring doesn't take part in the
+// relaxation, its force being applied by accelerate.
+func (c *SpringConstraint) Solve(*Context) {}
+
+// accelerate implements the forceConstraint interface.
+func (c *SpringConstraint) accelerate(ps Particles) {
+	delta := ps.Position(c.B).Sub(ps.Position(c.A))
+	dist := delta.Len()
+	if dist == 0 {
+		return
+	}
+	n := delta.Mul(1 / dist)
+
+	force := c.Stiffness*(dist-c.RestLength) + c.Damping*ps.Velocity(c.B).Sub(ps.Velocity(c.A)).Dot(n)
+	ps.applyForce(c.A, c.B, n.Mul(force))
+}
+
+// Strain implements the Breakable interface.
+func (c *SpringConstraint) Strain(ps Particles) float64 {
+	return strain(ps, c.A, c.B, c.RestLength)
+}
+
+// BreakStrain implements the Breakable interface.
+func (c *SpringConstraint) BreakStrain() float64 {
+	return c.BreakAt
+}
+
+// AngleConstraint keeps the angle between the B-A and B-C segments at a rest angle,
+// where B is the vertex of the angle. The angle is measured in radians.
+// It's solved by relaxation with both solvers.
+type AngleConstraint struct {
+	A, B, C   int
+	Angle     float64
+	Stiffness float64 // the fraction of the error corrected on each relaxation, in the [0, 1] range
+}
+
+// NewAngleConstraint creates a new angle constraint with b as the vertex of the angle.
+func NewAngleConstraint(a, b, c int, angle, stiffness float64) *AngleConstraint {
+	return &AngleConstraint{A: a, B: b, C: c, Angle: angle, Stiffness: stiffness}
+}
+
+// Indices implements the Constraint interface.
+func (c *AngleConstraint) Indices() []int {
+	return []int{c.A, c.B, c.C}
+}
+
+// Remap implements the Remapper interface.
+func (c *AngleConstraint) Remap(index []int) {
+	c.A, c.B, c.C = index[c.A], index[c.B], index[c.C]
+}
+
+// Solve implements the Constraint interface. The error is corrected by rotating
+// the two arms of the angle around the vertex in opposite directions.
+func (c *AngleConstraint) Solve(ctx *Context) {
+	ps := ctx.Particles
+	vertex := ps.Position(c.B)
+	ba := ps.Position(c.A).Sub(vertex)
+	bc := ps.Position(c.C).Sub(vertex)
+
+	angle := math.Atan2(ba.X*bc.Y-ba.Y*bc.X, ba.Dot(bc))
+	diff := math.Remainder(angle-c.Angle, 2*math.Pi) * c.Stiffness
+
+	// The rotation is distributed between the arms proportionally to their inverse mass.
+	wa, wc := ps.InvMass(c.A), ps.InvMass(c.C)
+	if wa+wc == 0 {
+		return
+	}
+	ps.SetPosition(c.A, vertex.Add(rotate(ba, diff*wa/(wa+wc))))
+	ps.SetPosition(c.C, vertex.Add(rotate(bc, -diff*wc/(wa+wc))))
+}
+
+// AttachmentConstraint attaches a particle to a fixed point of the world.
+type AttachmentConstraint struct {
+	P          int
+	Anchor     Vec2
+	Stiffness  float64 // the fraction of the error corrected on each relaxation, in the [0, 1] range
+	Compliance float64 // the inverse of the stiffness used by the XPBD solver, zero being rigid
+
+	lambda float64
+}
+
+// NewAttachmentConstraint creates a new attachment of the particle p to the anchor point.
+func NewAttachmentConstraint(p int, anchor Vec2, stiffness float64) *AttachmentConstraint {
+	return &AttachmentConstraint{P: p, Anchor: anchor, Stiffness: stiffness}
+}
+
+// Indices implements the Constraint interface.
+func (c *AttachmentConstraint) Indices() []int {
+	return []int{c.P}
+}
+
+// Remap implements the Remapper interface.
+func (c *AttachmentConstraint) Remap(index []int) {
+	c.P = index[c.P]
+}
+
+// Solve implements the Constraint interface.
+func (c *AttachmentConstraint) Solve(ctx *Context) {
+	ps := ctx.Particles
+	if ps.InvMass(c.P) == 0 {
+		return
+	}
+	pos := ps.Position(c.P)
+	if ctx.Params.Solver == SolverXPBD {
+		delta := pos.Sub(c.Anchor)
+		dist := delta.Len()
+		if dist == 0 {
+			return
+		}
+		w := ps.InvMass(c.P)
+		alpha := c.Compliance / (ctx.Dt * ctx.Dt)
+		dl := (-dist - alpha*c.lambda) / (w + alpha)
+		c.lambda += dl
+		ps.SetPosition(c.P, pos.Add(delta.Mul(dl*w/dist)))
+		return
+	}
+	ps.SetPosition(c.P, pos.Add(c.Anchor.Sub(pos).Mul(c.Stiffness)))
+}
+
+func (c *AttachmentConstraint) resetLambda() {
+	c.lambda = 0
+}
+
+// solveDistance moves the particles a and b towards the distance defined by length.
+// With maxOnly set, the constraint is solved only when the particles are farther away.
+func solveDistance(ps Particles, a, b int, length, stiffness float64, maxOnly bool) {
+	delta := ps.Position(b).Sub(ps.Position(a))
+	dist := delta.Len()
+	if dist == 0 || maxOnly && dist <= length {
+		return
+	}
+	ps.applyCorrection(a, b, delta.Mul((dist-length)/dist*stiffness))
+}
+
+// rotate rotates the vector v by the angle theta.
+func rotate(v Vec2, theta float64) Vec2 {
+	sin, cos := math.Sincos(theta)
+	return Vec(v.X*cos-v.Y*sin, v.X*sin+v.Y*cos)
+}
@@ -0,0 +1,108 @@
+package physics
+
+import "math"
+
+// tearRateWindow is the time span over which the tear rate is averaged, in seconds.
+const tearRateWindow = 1.0
+
+// Diagnostics holds the energy, the strain and the size of a cloth, measured
+// at the end of the last simulation step. They are useful for comparing the
+// stability of the solvers and the integrators, and for spotting the blow ups.
+type Diagnostics struct {
+	Time            float64 // the simulation time of the measurement, in seconds
+	KineticEnergy   float64 // the sum of ½mv² over the moving particles
+	PotentialEnergy float64 // the gravitational potential energy, relative to the bottom of the world
+	MaxStrain       float64 // the largest absolute strain of the breakable constraints
+	MeanStrain      float64 // the mean absolute strain of the breakable constraints
+	Particles       int     // the number of active particles
+	Pinned          int     // the number of active pinned particles
+	Constraints     int     // the number of constraints solved on the active particles
+	Tears           int     // the number of constraints broken during the last step
+	TearRate        float64 // the number of constraints broken per second, averaged over the last second
+}
+
+// tearSample is the number of constraints broken during the step ending at time.
+type tearSample struct {
+	time  float64
+	count int
+}
+
+// tearCounter counts the torn constraints over the recent steps.
+type tearCounter struct {
+	step    int // the constraints broken during the current step
+	last    int // the constraints broken during the last completed step
+	samples []tearSample
+}
+
+// commit closes the step ending at the time t, dropping the samples outside of the averaging window.
+func (tc *tearCounter) commit(t float64) {
+	tc.last, tc.step = tc.step, 0
+	if tc.last > 0 {
+		tc.samples = append(tc.samples, tearSample{time: t, count: tc.last})
+	}
+	var n int
+	for n < len(tc.samples) && tc.samples[n].time <= t-tearRateWindow {
+		n++
+	}
+	tc.samples = append(tc.samples[:0], tc.samples[n:]...)
+}
+
+// rate returns the number of constraints broken per second.
+func (tc *tearCounter) rate() float64 {
+	var sum int
+	for _, s := range tc.samples {
+		sum += s.count
+	}
+	return float64(sum) / tearRateWindow
+}
+
+// Diagnostics measures the cloth at the end of the last simulation step. The gravity and
+// the bottom of the world are taken from the world the cloth was added to, otherwise
+// the potential energy is measured with the default gravity relative to the origin.
+func (c *Cloth) Diagnostics() Diagnostics {
+	params, bottom := DefaultParams(), 0.0
+	d := Diagnostics{Tears: c.tears.last, TearRate: c.tears.rate()}
+	if c.world != nil {
+		params, bottom = c.world.params, c.world.Bounds.Height
+		d.Time = c.world.time
+	}
+
+	ps := &c.particles
+	for i, pos := range ps.pos {
+		if !ps.active(i) {
+			continue
+		}
+		d.Particles++
+		if ps.pinned(i) {
+			d.Pinned++
+		}
+		v := ps.vel[i]
+		d.KineticEnergy += ps.mass[i] * v.Dot(v) / 2
+		// The y axis points downwards, as the gravity does.
+		d.PotentialEnergy += ps.mass[i] * params.Gravity * (bottom - pos.Y)
+	}
+
+	var strained int
+	for _, ct := range c.constraints.items {
+		if ct == nil || !c.isActive(ct) {
+			continue
+		}
+		// The disabled lattice sticks are not solved, so they are neither counted nor strained.
+		if s, ok := ct.(*stick); ok && s.stiffness(params) == 0 {
+			continue
+		}
+		d.Constraints++
+
+		if b, ok := ct.(Breakable); ok {
+			// Both the stretched and the compressed constraints are strained.
+			strain := math.Abs(b.Strain(c.Particles()))
+			d.MaxStrain = math.Max(d.MaxStrain, strain)
+			d.MeanStrain += strain
+			strained++
+		}
+	}
+	if strained > 0 {
+		d.MeanStrain /= float64(strained)
+	}
+	return d
+}
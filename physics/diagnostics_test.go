@@ -0,0 +1,39 @@
+package physics
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestDiagnosticsConstraints(t *testing.T) {
+	// The 11x6 grid is held by 115 structural sticks, 100 shear and 98 bend sticks.
+	c := NewCloth(100, 50, 10, color.NRGBA{})
+	c.Init(0, 0)
+
+	w := NewWorld(400, 400)
+	p := DefaultParams()
+	p.ShearStiffness = 0.5
+	p.BendStiffness = 0.5
+	if err := w.SetParams(p); err != nil {
+		t.Fatal(err)
+	}
+	w.Add(c)
+	w.Step(0.022)
+	if got := c.Diagnostics().Constraints; got != 313 {
+		t.Fatalf("got %d constraints with the lattice sticks enabled, want 313", got)
+	}
+
+	// The disabled sticks are left in the constraint store until the next step.
+	p.ShearStiffness = 0
+	p.BendStiffness = 0
+	if err := w.SetParams(p); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Diagnostics().Constraints; got != 115 {
+		t.Fatalf("got %d constraints before the step, want 115", got)
+	}
+	w.Step(0.022)
+	if got := c.Diagnostics().Constraints; got != 115 {
+		t.Fatalf("got %d constraints after the step, want 115", got)
+	}
+}
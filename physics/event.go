@@ -0,0 +1,179 @@
+package physics
+
+// EventType identifies the kind of a simulation event.
+type EventType int
+
+const (
+	// EventConstraintBroken is emitted when a constraint is torn apart by its strain.
+	EventConstraintBroken EventType = iota
+	// EventParticlePinned is emitted when a particle gets pinned.
+	EventParticlePinned
+	// EventParticleUnpinned is emitted when a pinned particle is released.
+	EventParticleUnpinned
+	// EventParticleDeactivated is emitted when a particle is removed from the simulation.
+	EventParticleDeactivated
+	// EventPieceDetached is emitted when a piece is torn off from the rest of the cloth.
+	EventPieceDetached
+	// EventClothReset is emitted when the cloth is reset to its initial state.
+	EventClothReset
+)
+
+// String returns the human readable name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventConstraintBroken:
+		return "Constraint broken"
+	case EventParticlePinned:
+		return "Particle pinned"
+	case EventParticleUnpinned:
+		return "Particle unpinned"
+	case EventParticleDeactivated:
+		return "Particle deactivated"
+	case EventPieceDetached:
+		return "Piece detached"
+	case EventClothReset:
+		return "Cloth reset"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event describes something that happened with a cloth of the world.
+type Event struct {
+	Type       EventType
+	Time       float64 // the simulation time of the event, in seconds
+	Cloth      *Cloth
+	Particles  []int      // the index of the particles involved in the event
+	Constraint Constraint // the broken constraint, nil for the other events
+	Piece      *Piece     // the detached piece, nil for the other events
+}
+
+// subscriber is a function registered for the events of the types included in the mask.
+type subscriber struct {
+	id   int
+	fn   func(Event)
+	mask uint64
+}
+
+// Subscribe registers fn to be called with the events of the provided types,
+// or with all the events if no type is provided. The events emitted during a
+// simulation step are delivered in order at the end of the step, so the handlers
+// are free to modify the world. The returned function cancels the subscription.
+func (w *World) Subscribe(fn func(Event), types ...EventType) (cancel func()) {
+	mask := ^uint64(0)
+	if len(types) > 0 {
+		mask = 0
+		for _, t := range types {
+			mask |= 1 << t
+		}
+	}
+	w.lastSubscriber++
+	id := w.lastSubscriber
+	w.subscribers = append(w.subscribers, subscriber{id: id, fn: fn, mask: mask})
+
+	return func() {
+		for i, s := range w.subscribers {
+			if s.id == id {
+				w.subscribers = append(w.subscribers[:i], w.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// subscribed reports if there is a subscriber for the events of type t.
+func (w *World) subscribed(t EventType) bool {
+	for _, s := range w.subscribers {
+		if s.mask&(1<<t) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// emit delivers the event to the subscribers, or queues it while the world is stepped.
+func (w *World) emit(e Event) {
+	if !w.subscribed(e.Type) {
+		return
+	}
+	e.Time = w.time
+	if w.stepping {
+		w.events = append(w.events, e)
+		return
+	}
+	w.dispatch(e)
+}
+
+// flush delivers the events queued during the simulation step.
+func (w *World) flush() {
+	// The handlers can emit new events, which are delivered right away.
+	events := w.events
+	w.events = nil
+	for _, e := range events {
+		w.dispatch(e)
+	}
+}
+
+func (w *World) dispatch(e Event) {
+	for _, s := range w.subscribers {
+		if s.mask&(1<<e.Type) != 0 {
+			s.fn(e)
+		}
+	}
+}
+
+// emit sends the event to the world the cloth was added to.
+func (c *Cloth) emit(e Event) {
+	if c.world == nil {
+		return
+	}
+	e.Cloth = c
+	c.world.emit(e)
+}
+
+// emitParticle sends an event involving the particle i.
+func (c *Cloth) emitParticle(t EventType, i int) {
+	c.emit(Event{Type: t, Particles: []int{i}})
+}
+
+// detectDetached emits an event for each piece torn off since the pieces were last updated.
+// A piece split into multiple parts keeps going on as its largest part, while the rest of
+// the parts are reported as detached.
+func (c *Cloth) detectDetached() {
+	if c.world == nil || !c.world.subscribed(EventPieceDetached) {
+		return
+	}
+	if !c.pieces.outdated(c) {
+		return
+	}
+	// The previous pieces are not comparable with the current ones after a reset.
+	prev := append([]int(nil), c.pieces.label...)
+	pieces := c.Pieces()
+	if len(prev) != c.particles.len() || len(pieces) <= 1 {
+		return
+	}
+
+	// The parts of each previous piece, grouped by the previous piece of their first particle.
+	parts := make([][]int, len(prev))
+	for i, piece := range pieces {
+		if origin := prev[piece.Particles[0]]; origin >= 0 {
+			parts[origin] = append(parts[origin], i)
+		}
+	}
+	for _, group := range parts {
+		if len(group) < 2 {
+			continue
+		}
+		largest := group[0]
+		for _, i := range group[1:] {
+			if len(pieces[i].Particles) > len(pieces[largest].Particles) {
+				largest = i
+			}
+		}
+		for _, i := range group {
+			if i != largest {
+				c.emit(Event{Type: EventPieceDetached, Particles: pieces[i].Particles, Piece: &pieces[i]})
+			}
+		}
+	}
+}
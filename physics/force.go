@@ -0,0 +1,156 @@
+package physics
+
+import "math"
+
+// Force is an external force field evaluated for each particle on every substep.
+// The returned force is divided by the particle mass, so the same force accelerates
+// the heavier particles less. Custom force fields can be registered by implementing
+// this interface and adding them to the world.
+type Force interface {
+	// Force returns the force acting on a particle found at the pos position
+	// and moving with the vel velocity at the t simulation time.
+	Force(pos, vel Vec2, t float64) Vec2
+}
+
+// Falloff defines how the strength of a force field decreases with the distance from its center.
+type Falloff int
+
+const (
+	// FalloffConstant keeps the same strength inside the whole radius.
+	FalloffConstant Falloff = iota
+	// FalloffLinear decreases the strength linearly to zero at the radius.
+	FalloffLinear
+	// FalloffQuadratic decreases the strength quadratically, concentrating it around the center.
+	FalloffQuadratic
+	// FalloffSmooth decreases the strength along a smoothstep curve.
+	FalloffSmooth
+)
+
+// Weight returns the strength multiplier in the [0, 1] range at dist distance from the center.
+// A zero radius means that the force field has an infinite extent and it doesn't fall off.
+func (f Falloff) Weight(dist, radius float64) float64 {
+	if radius == 0 {
+		return 1
+	}
+	if dist >= radius {
+		return 0
+	}
+	t := 1 - dist/radius
+
+	switch f {
+	case FalloffLinear:
+		return t
+	case FalloffQuadratic:
+		return t * t
+	case FalloffSmooth:
+		return smoothstep(t)
+	default:
+		return 1
+	}
+}
+
+// PointForce attracts the particles towards its center, or repels them with a negative strength.
+type PointForce struct {
+	Center   Vec2
+	Strength float64
+	Radius   float64
+	Falloff  Falloff
+}
+
+// NewAttractor creates a new point force attracting the particles towards the center.
+func NewAttractor(center Vec2, strength, radius float64) *PointForce {
+	return &PointForce{Center: center, Strength: strength, Radius: radius, Falloff: FalloffLinear}
+}
+
+// NewRepulsor creates a new point force repelling the particles from the center.
+func NewRepulsor(center Vec2, strength, radius float64) *PointForce {
+	return &PointForce{Center: center, Strength: -strength, Radius: radius, Falloff: FalloffLinear}
+}
+
+// Force implements the Force interface.
+func (f *PointForce) Force(pos, _ Vec2, _ float64) Vec2 {
+	delta := f.Center.Sub(pos)
+	dist := delta.Len()
+	if dist == 0 {
+		return Vec2{}
+	}
+	return delta.Mul(f.Strength * f.Falloff.Weight(dist, f.Radius) / dist)
+}
+
+// VortexForce swirls the particles around its center, counter clockwise
+// with a positive and clockwise with a negative strength.
+type VortexForce struct {
+	Center   Vec2
+	Strength float64
+	Radius   float64
+	Falloff  Falloff
+}
+
+// NewVortex creates a new vortex around the center.
+func NewVortex(center Vec2, strength, radius float64) *VortexForce {
+	return &VortexForce{Center: center, Strength: strength, Radius: radius, Falloff: FalloffLinear}
+}
+
+// Force implements the Force interface.
+func (f *VortexForce) Force(pos, _ Vec2, _ float64) Vec2 {
+	delta := pos.Sub(f.Center)
+	dist := delta.Len()
+	if dist == 0 {
+		return Vec2{}
+	}
+	// The tangent is the radial direction rotated by 90 degrees.
+	tangent := Vec(delta.Y, -delta.X).Mul(1 / dist)
+	return tangent.Mul(f.Strength * f.Falloff.Weight(dist, f.Radius))
+}
+
+// DirectionalForce pushes the particles in the same direction. With a non zero
+// radius the field is limited to the area around its center.
+type DirectionalForce struct {
+	Direction float64 // the direction of the force, in radians
+	Strength  float64
+	Center    Vec2
+	Radius    float64
+	Falloff   Falloff
+}
+
+// NewDirectionalForce creates a new directional force field with an infinite extent.
+func NewDirectionalForce(direction, strength float64) *DirectionalForce {
+	return &DirectionalForce{Direction: direction, Strength: strength}
+}
+
+// Force implements the Force interface.
+func (f *DirectionalForce) Force(pos, _ Vec2, _ float64) Vec2 {
+	w := f.Falloff.Weight(pos.Sub(f.Center).Len(), f.Radius)
+	return Vec(math.Cos(f.Direction), math.Sin(f.Direction)).Mul(f.Strength * w)
+}
+
+// applyConstraintForces adds the acceleration caused by the force constraints to the cloth particles.
+func (c *Cloth) applyConstraintForces() {
+	for _, ct := range c.constraints.items {
+		// The lattice sticks are by far the most common constraints, so they are skipped first.
+		if _, ok := ct.(*stick); ok || ct == nil {
+			continue
+		}
+		if fc, ok := ct.(forceConstraint); ok && c.isActive(fc) {
+			fc.accelerate(c.Particles())
+		}
+	}
+}
+
+// applyForces adds the acceleration caused by the force fields to the cloth particles.
+func (c *Cloth) applyForces(forces []Force, t float64) {
+	if len(forces) == 0 {
+		return
+	}
+	ps := &c.particles
+	for i, pos := range ps.pos {
+		if !ps.free(i) {
+			continue
+		}
+		var force Vec2
+		for _, f := range forces {
+			force = force.Add(f.Force(pos, ps.vel[i], t))
+		}
+		ps.acc[i] = ps.acc[i].Add(force.Mul(ps.invMass[i]))
+	}
+}
@@ -0,0 +1,94 @@
+package physics
+
+// ParticleState is the kinematic state of a particle advanced by an integrator.
+type ParticleState struct {
+	Pos  Vec2 // the current position
+	Prev Vec2 // the position at the previous substep
+	Vel  Vec2 // the velocity at the end of the previous substep
+}
+
+// Timestep describes the substep a particle is integrated over.
+type Timestep struct {
+	Dt      float64 // the duration of the substep
+	PrevDt  float64 // the duration of the previous substep, zero on the first one
+	Damping float64 // the fraction of the velocity kept over the substep
+}
+
+// Integrator advances the particles in time before the constraints are solved. The velocity
+// returned by the integrator is kept, and only the corrections the constraints and the collisions
+// make to the particle displacement are added to it, so the integrators with an explicit velocity
+// see the corrected velocity too, and they can be switched between the steps.
+type Integrator interface {
+	// Integrate returns the state of the particle p after the ts substep,
+	// given the acceleration acting on it.
+	Integrate(p ParticleState, acc Vec2, ts Timestep) ParticleState
+}
+
+// PositionVerlet is the position Verlet integrator, which stores the velocity implicitly
+// as the difference of the last two positions. It's stable and cheap, but it assumes that
+// the timestep doesn't change, otherwise the velocity is scaled by the timestep ratio.
+type PositionVerlet struct{}
+
+// Integrate implements the Integrator interface.
+func (PositionVerlet) Integrate(p ParticleState, acc Vec2, ts Timestep) ParticleState {
+	// x(t+Δt)=2x(t)−x(t−Δt)+a(t)Δt2
+	pos := p.Pos.Add(p.Pos.Sub(p.Prev).Mul(ts.Damping)).Add(acc.Mul(ts.Dt * ts.Dt))
+	return ParticleState{Pos: pos, Prev: p.Pos, Vel: pos.Sub(p.Pos).Mul(1 / ts.Dt)}
+}
+
+// String returns the human readable name of the integrator.
+func (PositionVerlet) String() string {
+	return "Position Verlet"
+}
+
+// TimeCorrectedVerlet is the position Verlet integrator corrected for variable timesteps,
+// which scales the implicit velocity by the ratio of the current and the previous timestep.
+type TimeCorrectedVerlet struct{}
+
+// Integrate implements the Integrator interface.
+func (TimeCorrectedVerlet) Integrate(p ParticleState, acc Vec2, ts Timestep) ParticleState {
+	ratio := 1.0
+	if ts.PrevDt > 0 {
+		ratio = ts.Dt / ts.PrevDt
+	}
+	// x(t+Δt)=x(t)+(x(t)−x(t−Δt'))Δt/Δt'+a(t)Δt2
+	pos := p.Pos.Add(p.Pos.Sub(p.Prev).Mul(ts.Damping * ratio)).Add(acc.Mul(ts.Dt * ts.Dt))
+	return ParticleState{Pos: pos, Prev: p.Pos, Vel: pos.Sub(p.Pos).Mul(1 / ts.Dt)}
+}
+
+// String returns the human readable name of the integrator.
+func (TimeCorrectedVerlet) String() string {
+	return "Time-corrected Verlet"
+}
+
+// SemiImplicitEuler is the semi-implicit (symplectic) Euler integrator with explicit velocities,
+// which updates the velocity first and then moves the particle with the updated velocity.
+// Unlike the Verlet integrators, which damp only the velocity carried over from the previous
+// substep, it damps the updated velocity, so the cloth falls and settles more slowly.
+type SemiImplicitEuler struct{}
+
+// Integrate implements the Integrator interface.
+func (SemiImplicitEuler) Integrate(p ParticleState, acc Vec2, ts Timestep) ParticleState {
+	// v(t+Δt)=(v(t)+a(t)Δt)d
+	// x(t+Δt)=x(t)+v(t+Δt)Δt
+	vel := p.Vel.Add(acc.Mul(ts.Dt)).Mul(ts.Damping)
+	return ParticleState{Pos: p.Pos.Add(vel.Mul(ts.Dt)), Prev: p.Pos, Vel: vel}
+}
+
+// String returns the human readable name of the integrator.
+func (SemiImplicitEuler) String() string {
+	return "Semi-implicit Euler"
+}
+
+// updateVelocities adds to the velocity returned by the integrator the constraint and the collision
+// corrections, being the difference between the particle displacement over the substep of dt duration
+// and the displacement returned by the integrator.
+func (s *particleStore) updateVelocities(dt float64) {
+	for i, pos := range s.pos {
+		if s.free(i) {
+			s.vel[i] = s.vel[i].Add(pos.Sub(s.prev[i]).Sub(s.moved[i]).Mul(1 / dt))
+		} else {
+			s.vel[i] = Vec2{}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package physics
+
+import (
+	"image/color"
+	"testing"
+)
+
+// simulate drops a cloth pinned by its corners with the provided integrator, and returns the
+// position of its particles after the steps. The substeps function returns the number of substeps
+// of each step, which changes the duration of the substeps when it doesn't return the same value.
+func simulate(t *testing.T, integrator Integrator, substeps func(step int) int) []Vec2 {
+	t.Helper()
+
+	c := NewCloth(100, 50, 10, color.NRGBA{})
+	c.PinPattern = PinCorners
+	c.Init(50, 50)
+
+	w := NewWorld(400, 400)
+	w.SetIntegrator(integrator)
+	w.Add(c)
+
+	p := DefaultParams()
+	for i := 0; i < 60; i++ {
+		p.Substeps = substeps(i)
+		if err := w.SetParams(p); err != nil {
+			t.Fatal(err)
+		}
+		w.Step(0.022)
+	}
+	return append([]Vec2(nil), c.particles.pos...)
+}
+
+func TestIntegrators(t *testing.T) {
+	fixed := func(int) int { return 2 }
+	varying := func(i int) int { return 1 + i%3 }
+
+	tests := []struct {
+		name     string
+		a, b     Integrator
+		substeps func(int) int
+		same     bool
+	}{
+		{"verlet/time-corrected/fixed", PositionVerlet{}, TimeCorrectedVerlet{}, fixed, true},
+		{"verlet/time-corrected/varying", PositionVerlet{}, TimeCorrectedVerlet{}, varying, false},
+		{"verlet/euler/fixed", PositionVerlet{}, SemiImplicitEuler{}, fixed, false},
+		{"time-corrected/euler/varying", TimeCorrectedVerlet{}, SemiImplicitEuler{}, varying, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := simulate(t, tt.a, tt.substeps), simulate(t, tt.b, tt.substeps)
+
+			var diff float64
+			for i := range a {
+				diff = max(diff, a[i].Sub(b[i]).Len())
+			}
+			if tt.same && diff != 0 {
+				t.Errorf("the particles moved apart by %g", diff)
+			}
+			// The integrators are expected to diverge by more than the rounding errors.
+			if !tt.same && diff < 1e-3 {
+				t.Errorf("the particles moved apart by only %g", diff)
+			}
+		})
+	}
+}
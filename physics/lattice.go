@@ -0,0 +1,112 @@
+package physics
+
+// latticeSet holds the shear or the bend sticks of a cloth. The sticks are kept in the
+// constraint store only while their stiffness is nonzero, so the disabled sticks are not
+// walked by the solver, the tearing and the collisions on each step.
+type latticeSet struct {
+	sticks  []*stick
+	enabled bool
+}
+
+// lattice returns the set holding the sticks of the shear or the bend kind.
+func (c *Cloth) lattice(kind constraintKind) *latticeSet {
+	return &c.latticeSets[kind-shear]
+}
+
+// syncLattice moves the shear and bend sticks in or out of the constraint store when their
+// stiffness has been raised from or set to zero. The sticks torn while they were enabled,
+// and the ones left without an intact path while they were disabled, are dropped for good.
+func (c *Cloth) syncLattice() {
+	var changed bool
+	for k, enabled := range c.latticeEnabled() {
+		set := &c.latticeSets[k]
+		if set.enabled == enabled {
+			continue
+		}
+		set.enabled, changed = enabled, true
+
+		sticks := set.sticks[:0]
+		for _, s := range set.sticks {
+			if enabled {
+				if !c.isActive(s) {
+					c.forget(s)
+					continue
+				}
+				c.constraints.add(s)
+			} else if !c.constraints.remove(s) {
+				continue
+			}
+			sticks = append(sticks, s)
+		}
+		clear(set.sticks[len(sticks):])
+		set.sticks = sticks
+	}
+	// The disabled sticks are dropped right away instead of being left as tombstones.
+	if changed {
+		c.constraints.compact()
+	}
+}
+
+// remapLattice moves the particles of the disabled sticks to their new index after the compaction,
+// dropping the sticks of the removed particles. The enabled sticks are remapped by the store,
+// so only the torn ones are dropped from their set.
+func (c *Cloth) remapLattice(index []int) {
+	for k := range c.latticeSets {
+		set := &c.latticeSets[k]
+		sticks := set.sticks[:0]
+		for _, s := range set.sticks {
+			if set.enabled {
+				if s.removed {
+					continue
+				}
+			} else {
+				if index[s.idx[0]] < 0 || index[s.idx[1]] < 0 {
+					continue
+				}
+				s.Remap(index)
+			}
+			sticks = append(sticks, s)
+		}
+		clear(set.sticks[len(sticks):])
+		set.sticks = sticks
+	}
+}
+
+// stickKey returns the key of the stick connecting the particles a and b in the stick lookup.
+func stickKey(a, b int) [2]int {
+	return [2]int{min(a, b), max(a, b)}
+}
+
+// findStick returns the lattice stick connecting the particles a and b, including the disabled ones,
+// except the sticks left without an intact path, which are not going to be enabled anymore.
+func (c *Cloth) findStick(a, b int) *stick {
+	if s := c.sticks[stickKey(a, b)]; s != nil && s.intact(&c.particles) {
+		return s
+	}
+	return nil
+}
+
+// forget drops the torn or removed stick from the stick lookup.
+func (c *Cloth) forget(s *stick) {
+	if key := stickKey(s.idx[0], s.idx[1]); c.sticks[key] == s {
+		delete(c.sticks, key)
+	}
+}
+
+// indexSticks rebuilds the stick lookup after the particles have been moved to new indices.
+func (c *Cloth) indexSticks() {
+	clear(c.sticks)
+	for _, ct := range c.constraints.items {
+		if s, ok := ct.(*stick); ok {
+			c.sticks[stickKey(s.idx[0], s.idx[1])] = s
+		}
+	}
+	for _, set := range c.latticeSets {
+		if set.enabled {
+			continue
+		}
+		for _, s := range set.sticks {
+			c.sticks[stickKey(s.idx[0], s.idx[1])] = s
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package physics
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// bendAngle is the minimum angle between two edges meeting at a vertex, for which the
+// vertices at the other end of the edges are connected by a bend constraint. The edges
+// running along a nearly straight line are the ones resisting the folding of the cloth.
+const bendAngle = 150 * math.Pi / 180
+
+// mesh is the triangle mesh a cloth is built of, the vertices being relative to the cloth origin.
+type mesh struct {
+	vertices  []Vec2
+	triangles [][3]int
+}
+
+// NewMeshCloth creates a new cloth from an arbitrary triangle mesh, given its vertices and the
+// index of the vertices of each triangle. The constraints are derived from the mesh edges:
+//
+//   - each edge is connected by a structural constraint,
+//   - the opposite vertices of the two triangles sharing an edge are connected by a shear constraint,
+//   - the vertices at the other end of two edges meeting at a vertex along a nearly straight line
+//     are connected by a bend constraint.
+//
+// As with NewCloth, the cloth is built by Init, which places the mesh with its origin at the
+// provided position. The pin patterns need a grid, so only PinCustom applies to the mesh
+// cloths, the PinIndices being the index of the pinned vertices.
+func NewMeshCloth(vertices []Vec2, triangles [][3]int, col color.NRGBA) (*Cloth, error) {
+	if len(triangles) == 0 {
+		return nil, fmt.Errorf("the mesh should have at least one triangle")
+	}
+	for i, t := range triangles {
+		for _, v := range t {
+			if v < 0 || v >= len(vertices) {
+				return nil, fmt.Errorf("triangle %d: vertex index %d is outside of the [0, %d) range", i, v, len(vertices))
+			}
+		}
+		if t[0] == t[1] || t[1] == t[2] || t[2] == t[0] {
+			return nil, fmt.Errorf("triangle %d: the vertices should be distinct, got %v", i, t)
+		}
+	}
+
+	var size Vec2
+	for _, v := range vertices {
+		size = Vec(math.Max(size.X, v.X), math.Max(size.Y, v.Y))
+	}
+	return &Cloth{
+		Width:  int(math.Ceil(size.X)),
+		Height: int(math.Ceil(size.Y)),
+		color:  col,
+		mesh: &mesh{
+			vertices:  append([]Vec2(nil), vertices...),
+			triangles: append([][3]int(nil), triangles...),
+		},
+	}, nil
+}
+
+// initMesh builds the cloth from its mesh, placing the mesh origin at the {posX, posY} position.
+func (c *Cloth) initMesh(posX, posY int) {
+	origin := Vec(float64(posX), float64(posY))
+	for _, v := range c.mesh.vertices {
+		pos := origin.Add(v)
+		i := c.particles.add(pos.X, pos.Y)
+		c.particles.set(i, particlePinned, c.PinPattern == PinCustom && c.isListed(i))
+		c.grid = append(c.grid, i)
+	}
+
+	// The connected vertex pairs are tracked, so each pair is connected only once,
+	// by the constraint of the first kind deriving it. It returns nil for the pairs
+	// already connected.
+	connected := make(map[[2]int]*stick)
+	connect := func(a, b int, kind constraintKind) *stick {
+		key := [2]int{min(a, b), max(a, b)}
+		if connected[key] != nil {
+			return nil
+		}
+		connected[key] = c.connect(a, b, c.mesh.vertices[b].Sub(c.mesh.vertices[a]).Len(), kind)
+		return connected[key]
+	}
+	// edge returns the structural stick of the mesh edge a-b.
+	edge := func(a, b int) *stick {
+		if s := connected[[2]int{min(a, b), max(a, b)}]; s != nil && s.kind == structural {
+			return s
+		}
+		return nil
+	}
+
+	// The opposite vertex of each triangle sharing an edge, indexed by the edge.
+	opposite := make(map[[2]int][]int)
+	neighbours := make([][]int, len(c.mesh.vertices))
+	for _, t := range c.mesh.triangles {
+		for k := 0; k < 3; k++ {
+			a, b := t[k], t[(k+1)%3]
+			key := [2]int{min(a, b), max(a, b)}
+			if _, ok := opposite[key]; !ok {
+				neighbours[a] = append(neighbours[a], b)
+				neighbours[b] = append(neighbours[b], a)
+			}
+			opposite[key] = append(opposite[key], t[(k+2)%3])
+		}
+		c.AddTriangle(t[0], t[1], t[2])
+	}
+
+	for _, t := range c.mesh.triangles {
+		for k := 0; k < 3; k++ {
+			connect(t[k], t[(k+1)%3], structural)
+		}
+	}
+	for _, t := range c.mesh.triangles {
+		for k := 0; k < 3; k++ {
+			a, b := t[k], t[(k+1)%3]
+			if o := opposite[[2]int{min(a, b), max(a, b)}]; len(o) == 2 {
+				// The shear stick spans the two edge paths around the shared edge.
+				if s := connect(o[0], o[1], shear); s != nil {
+					s.addPath(edge(o[0], a), edge(a, o[1]), a)
+					s.addPath(edge(o[0], b), edge(b, o[1]), b)
+				}
+			}
+		}
+	}
+	for v, ns := range neighbours {
+		center := c.mesh.vertices[v]
+		for _, a := range ns {
+			// Each neighbour is paired with the neighbour found in the most opposite direction.
+			da := c.mesh.vertices[a].Sub(center)
+			best, angle := -1, bendAngle
+			for _, b := range ns {
+				db := c.mesh.vertices[b].Sub(center)
+				if ab := math.Acos(clamp(da.Dot(db)/(da.Len()*db.Len()), -1, 1)); ab >= angle {
+					best, angle = b, ab
+				}
+			}
+			if best >= 0 {
+				if s := connect(a, best, bend); s != nil {
+					s.addPath(edge(a, v), edge(v, best), v)
+				}
+			}
+		}
+	}
+	c.isInitialized = true
+}
+
+// clamp restricts v to the [lo, hi] range.
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(v, hi))
+}
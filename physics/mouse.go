@@ -1,17 +1,11 @@
 package physics
 
-import (
-	"gioui.org/f32"
-	"gioui.org/io/pointer"
-	"gioui.org/unit"
-)
-
 type Mouse struct {
 	x, y       float64
 	px, py     float64
 	force      float64
-	scrollY    unit.Dp
-	maxScrollY unit.Dp
+	scrollY    float64
+	maxScrollY float64
 	leftDown   bool
 	rightDown  bool
 	isDragging bool
@@ -26,8 +20,8 @@ func (m *Mouse) UpdatePosition(x, y float64) {
 	m.y = y
 }
 
-func (m *Mouse) GetCurrentPosition(ev pointer.Event) f32.Point {
-	return ev.Position
+func (m *Mouse) GetPosition() Vec2 {
+	return Vec(m.x, m.y)
 }
 
 func (m *Mouse) SetLeftButton() {
@@ -82,18 +76,18 @@ func (m *Mouse) ResetForce() {
 	m.force = 0
 }
 
-func (m *Mouse) SetScrollY(scrollY unit.Dp) {
+func (m *Mouse) SetScrollY(scrollY float64) {
 	m.scrollY = scrollY
 }
 
-func (m *Mouse) GetScrollY() unit.Dp {
+func (m *Mouse) GetScrollY() float64 {
 	return m.scrollY
 }
 
-func (m *Mouse) SetMaxScrollY(maxScrollY unit.Dp) {
+func (m *Mouse) SetMaxScrollY(maxScrollY float64) {
 	m.maxScrollY = maxScrollY
 }
 
-func (m *Mouse) GetMaxScrollY() unit.Dp {
+func (m *Mouse) GetMaxScrollY() float64 {
 	return m.maxScrollY
 }
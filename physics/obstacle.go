@@ -0,0 +1,248 @@
+package physics
+
+import "math"
+
+// collisionMargin is the minimum distance kept between the particles and the obstacle surfaces.
+const collisionMargin = 1
+
+// Shape is the geometry of a static obstacle.
+type Shape interface {
+	// Distance returns the signed distance between the point p and the shape surface,
+	// which is negative inside the shape, and the outward normal of the closest surface.
+	Distance(p Vec2) (float64, Vec2)
+}
+
+// Obstacle is a static rigid body the cloth collides with.
+type Obstacle struct {
+	Shape    Shape
+	Friction float64 // the fraction of the tangential velocity lost on contact, in the [0, 1] range
+}
+
+// Circle is a circle shaped obstacle.
+type Circle struct {
+	Center Vec2
+	Radius float64
+}
+
+// Distance implements the Shape interface.
+func (c Circle) Distance(p Vec2) (float64, Vec2) {
+	delta := p.Sub(c.Center)
+	dist := delta.Len()
+	if dist == 0 {
+		return -c.Radius, Vec(0, -1)
+	}
+	return dist - c.Radius, delta.Mul(1 / dist)
+}
+
+// Box is an axis-aligned rectangle obstacle defined by its top-left and bottom-right corner.
+type Box struct {
+	Min, Max Vec2
+}
+
+// Distance implements the Shape interface.
+func (b Box) Distance(p Vec2) (float64, Vec2) {
+	// The distance from each side, positive outside of that side.
+	left, right := b.Min.X-p.X, p.X-b.Max.X
+	top, bottom := b.Min.Y-p.Y, p.Y-b.Max.Y
+
+	dx, dy := max(left, right), max(top, bottom)
+	if dx <= 0 && dy <= 0 {
+		// The point is inside the box, so the closest side is the one with the largest distance.
+		switch max(left, right, top, bottom) {
+		case left:
+			return left, Vec(-1, 0)
+		case right:
+			return right, Vec(1, 0)
+		case top:
+			return top, Vec(0, -1)
+		default:
+			return bottom, Vec(0, 1)
+		}
+	}
+	closest := Vec(math.Min(math.Max(p.X, b.Min.X), b.Max.X), math.Min(math.Max(p.Y, b.Min.Y), b.Max.Y))
+	delta := p.Sub(closest)
+	dist := delta.Len()
+	return dist, delta.Mul(1 / dist)
+}
+
+// Capsule is a segment with rounded ends, defined by the segment end points and the radius.
+type Capsule struct {
+	A, B   Vec2
+	Radius float64
+}
+
+// Distance implements the Shape interface.
+func (c Capsule) Distance(p Vec2) (float64, Vec2) {
+	return Circle{Center: closestOnSegment(p, c.A, c.B), Radius: c.Radius}.Distance(p)
+}
+
+// Polygon is a convex polygon obstacle. The vertices can be listed in any winding order.
+type Polygon struct {
+	Points []Vec2
+}
+
+// Distance implements the Shape interface.
+func (poly Polygon) Distance(p Vec2) (float64, Vec2) {
+	n := len(poly.Points)
+	if n < 3 {
+		return math.Inf(1), Vec2{}
+	}
+	// The winding order defines on which side of the edges is the outside of the polygon.
+	var area float64
+	for i, a := range poly.Points {
+		b := poly.Points[(i+1)%n]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	sign := 1.0
+	if area < 0 {
+		sign = -1
+	}
+
+	inside := true
+	maxDist, maxNormal := math.Inf(-1), Vec2{}
+	minDist, minNormal := math.Inf(1), Vec2{}
+
+	for i, a := range poly.Points {
+		b := poly.Points[(i+1)%n]
+		edge := b.Sub(a)
+		length := edge.Len()
+		if length == 0 {
+			continue
+		}
+		normal := Vec(edge.Y, -edge.X).Mul(sign / length)
+		if d := p.Sub(a).Dot(normal); d > 0 {
+			inside = false
+		} else if d > maxDist {
+			maxDist, maxNormal = d, normal
+		}
+		// The distance from the outside is measured to the closest edge.
+		delta := p.Sub(closestOnSegment(p, a, b))
+		if d := delta.Len(); d < minDist {
+			minDist, minNormal = d, delta.Mul(1/d)
+		}
+	}
+	if inside {
+		return maxDist, maxNormal
+	}
+	return minDist, minNormal
+}
+
+// convex reports if the polygon is convex, which means that it turns in the same direction
+// at each vertex and it winds around only once. The collinear vertices are accepted.
+func convex(points []Vec2) bool {
+	var sign, turning float64
+	for i, a := range points {
+		b, c := points[(i+1)%len(points)], points[(i+2)%len(points)]
+		ab, bc := b.Sub(a), c.Sub(b)
+		cross := ab.X*bc.Y - ab.Y*bc.X
+		if cross*sign < 0 {
+			return false
+		}
+		if cross != 0 {
+			sign = cross
+		}
+		turning += math.Atan2(cross, ab.Dot(bc))
+	}
+	return sign != 0 && math.Abs(math.Abs(turning)-2*math.Pi) < 1e-6
+}
+
+// closestOnSegment returns the closest point to p on the segment a-b.
+func closestOnSegment(p, a, b Vec2) Vec2 {
+	ab := b.Sub(a)
+	l2 := ab.Dot(ab)
+	if l2 == 0 {
+		return a
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l2))
+	return a.Add(ab.Mul(t))
+}
+
+// collide pushes the cloth particles and sticks out of the obstacles, recording the obstacle
+// each particle has touched, so the friction can be applied at the end of the substep.
+func (c *Cloth) collide(obstacles []Obstacle) {
+	if len(obstacles) == 0 {
+		return
+	}
+	ps := &c.particles
+	if len(c.contacts) != len(ps.pos) {
+		c.contacts = make([]int, len(ps.pos))
+		for i := range c.contacts {
+			c.contacts[i] = -1
+		}
+	}
+	for i := range ps.pos {
+		if !ps.free(i) {
+			continue
+		}
+		for k, o := range obstacles {
+			if ps.collide(i, o) {
+				c.contacts[i] = k
+			}
+		}
+	}
+
+	// The sticks are tested at their midpoint, otherwise the
+	// small obstacles could slip through between the particles.
+	for _, ct := range c.constraints.items {
+		s, ok := ct.(*stick)
+		if !ok || s.kind != structural {
+			continue
+		}
+		i1, i2 := s.idx[0], s.idx[1]
+		if !ps.active(i1) || !ps.active(i2) {
+			continue
+		}
+		mid := ps.pos[i1].Add(ps.pos[i2]).Mul(0.5)
+		for _, o := range obstacles {
+			dist, normal := o.Shape.Distance(mid)
+			if dist >= collisionMargin {
+				continue
+			}
+			push := normal.Mul(collisionMargin - dist)
+			for _, i := range s.idx {
+				if !ps.pinned(i) {
+					ps.pos[i] = ps.pos[i].Add(push)
+				}
+			}
+		}
+	}
+}
+
+// collide pushes the particle i out of the obstacle. It reports whether they were in contact.
+func (s *particleStore) collide(i int, o Obstacle) bool {
+	dist, normal := o.Shape.Distance(s.pos[i])
+	if dist >= collisionMargin {
+		return false
+	}
+	s.pos[i] = s.pos[i].Add(normal.Mul(collisionMargin - dist))
+	return true
+}
+
+// rub applies the friction of the obstacles on the particles which have touched them during the
+// substep. It's applied only once per substep, otherwise it would compound with the iterations.
+func (c *Cloth) rub(obstacles []Obstacle) {
+	ps := &c.particles
+	for i, k := range c.contacts {
+		if k < 0 {
+			continue
+		}
+		c.contacts[i] = -1
+		if ps.free(i) {
+			ps.rub(i, obstacles[k])
+		}
+	}
+}
+
+// rub cancels the velocity of the particle i towards the obstacle,
+// while its tangential velocity is reduced by the obstacle friction.
+func (s *particleStore) rub(i int, o Obstacle) {
+	_, normal := o.Shape.Distance(s.pos[i])
+	pos := s.pos[i]
+	vel := pos.Sub(s.prev[i])
+	vn := normal.Mul(vel.Dot(normal))
+	vt := vel.Sub(vn).Mul(1 - o.Friction)
+	if vel.Dot(normal) > 0 {
+		vt = vt.Add(vn)
+	}
+	s.prev[i] = pos.Sub(vt)
+}
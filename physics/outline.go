@@ -0,0 +1,206 @@
+package physics
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// cornerAngle is the maximum angle of the outline corners which get a vertex of their own,
+// otherwise the lattice would round off the sharp corners, like the tip of a pennant.
+const cornerAngle = 150 * math.Pi / 180
+
+// outline is a closed polygon the cloth lattice is clipped to. It can be concave,
+// but it shouldn't intersect itself. The vertices can be listed in any winding order.
+type outline []Vec2
+
+// contains reports if the point p is inside the outline, using the even-odd rule.
+func (o outline) contains(p Vec2) bool {
+	var inside bool
+	for i, a := range o {
+		b := o[(i+len(o)-1)%len(o)]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// closest returns the closest point to p on the outline.
+func (o outline) closest(p Vec2) Vec2 {
+	best, dist := p, math.Inf(1)
+	for i, a := range o {
+		q := closestOnSegment(p, a, o[(i+1)%len(o)])
+		if d := q.Sub(p).Len(); d < dist {
+			best, dist = q, d
+		}
+	}
+	return best
+}
+
+// area returns the signed area of the outline.
+func (o outline) area() float64 {
+	var area float64
+	for i, a := range o {
+		b := o[(i+1)%len(o)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	return area / 2
+}
+
+// NewCircleCloth creates a new circular cloth, like a round tablecloth, with the provided radius.
+// The circle is enclosed by the square between the cloth origin and the {2*radius, 2*radius} point.
+func NewCircleCloth(radius float64, spacing int, col color.NRGBA) (*Cloth, error) {
+	return NewEllipseCloth(radius, radius, spacing, col)
+}
+
+// NewEllipseCloth creates a new elliptical cloth with the provided horizontal and vertical radius.
+// The ellipse is enclosed by the rectangle between the cloth origin and the {2*rx, 2*ry} point.
+func NewEllipseCloth(rx, ry float64, spacing int, col color.NRGBA) (*Cloth, error) {
+	if rx <= 0 || ry <= 0 {
+		return nil, fmt.Errorf("invalid ellipse radius: %g, %g", rx, ry)
+	}
+	// The outline segments are kept shorter than the lattice spacing,
+	// so the boundary vertices are snapped onto a smooth curve.
+	n := max(16, int(math.Ceil(2*math.Pi*math.Max(rx, ry)/(float64(spacing)/2))))
+	points := make([]Vec2, n)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		points[i] = Vec(rx+rx*math.Cos(angle), ry+ry*math.Sin(angle))
+	}
+	return newOutlineCloth(points, spacing, col)
+}
+
+// NewTriangleCloth creates a new triangular cloth, like a pennant, with the provided vertices.
+func NewTriangleCloth(a, b, c Vec2, spacing int, col color.NRGBA) (*Cloth, error) {
+	return NewPolygonCloth([]Vec2{a, b, c}, spacing, col)
+}
+
+// NewPolygonCloth creates a new cloth clipped to an arbitrary polygon, like a sail. The polygon
+// can be concave, but it shouldn't intersect itself. The vertices are relative to the cloth origin
+// and they can be listed in any winding order.
+func NewPolygonCloth(points []Vec2, spacing int, col color.NRGBA) (*Cloth, error) {
+	if len(points) < 3 {
+		return nil, fmt.Errorf("the polygon should have at least 3 points, got %d", len(points))
+	}
+	if outline(points).area() == 0 {
+		return nil, fmt.Errorf("the polygon has no area")
+	}
+	return newOutlineCloth(points, spacing, col)
+}
+
+// newOutlineCloth creates a mesh cloth from a lattice clipped to the outline. The lattice cells
+// with their center inside the outline are kept, while their corners found outside of it are
+// snapped onto the outline, so the boundary of the cloth follows the outline. Each cell is divided
+// into two triangles, the same way as the cells of the rectangular cloth.
+func newOutlineCloth(points outline, spacing int, col color.NRGBA) (*Cloth, error) {
+	if spacing <= 0 {
+		return nil, fmt.Errorf("invalid lattice spacing: %d", spacing)
+	}
+	s := float64(spacing)
+
+	lo, hi := Vec(math.Inf(1), math.Inf(1)), Vec(math.Inf(-1), math.Inf(-1))
+	for _, p := range points {
+		lo = Vec(math.Min(lo.X, p.X), math.Min(lo.Y, p.Y))
+		hi = Vec(math.Max(hi.X, p.X), math.Max(hi.Y, p.Y))
+	}
+	cols := int(math.Ceil((hi.X-lo.X)/s)) + 1
+	rows := int(math.Ceil((hi.Y-lo.Y)/s)) + 1
+
+	// vertex holds the index of the mesh vertex from each lattice position, -1 for the unused ones.
+	vertex := make([]int, cols*rows)
+	for i := range vertex {
+		vertex[i] = -1
+	}
+	var (
+		vertices []Vec2
+		boundary []bool // the vertex was snapped onto the outline
+	)
+	at := func(x, y int) int {
+		i := x + y*cols
+		if vertex[i] < 0 {
+			p := lo.Add(Vec(float64(x), float64(y)).Mul(s))
+			outside := !points.contains(p)
+			if outside {
+				p = points.closest(p)
+			}
+			vertex[i] = len(vertices)
+			vertices = append(vertices, p)
+			boundary = append(boundary, outside)
+		}
+		return vertex[i]
+	}
+
+	var cells [][2]int
+	for y := 1; y < rows; y++ {
+		for x := 1; x < cols; x++ {
+			center := lo.Add(Vec(float64(x)-0.5, float64(y)-0.5).Mul(s))
+			if points.contains(center) {
+				cells = append(cells, [2]int{x, y})
+				at(x-1, y-1)
+				at(x, y-1)
+				at(x-1, y)
+				at(x, y)
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return nil, fmt.Errorf("the outline is too small for the %d lattice spacing", spacing)
+	}
+
+	// The closest boundary vertex, within two lattice cells, is moved onto each sharp corner of the
+	// outline. The vertices inside the outline and the ones already moved onto a corner are left as they are.
+	snapped := make([]bool, len(vertices))
+	for i, p := range points {
+		prev, next := points[(i+len(points)-1)%len(points)], points[(i+1)%len(points)]
+		da, db := prev.Sub(p), next.Sub(p)
+		if da.Len() == 0 || db.Len() == 0 || math.Acos(clamp(da.Dot(db)/(da.Len()*db.Len()), -1, 1)) > cornerAngle {
+			continue
+		}
+		best, dist := -1, 2*s
+		for j, v := range vertices {
+			if d := v.Sub(p).Len(); d < dist && boundary[j] && !snapped[j] {
+				best, dist = j, d
+			}
+		}
+		if best >= 0 {
+			vertices[best], snapped[best] = p, true
+		}
+	}
+
+	// The snapping could collapse or flip some of the triangles, which are dropped.
+	var triangles [][3]int
+	minArea := s * s * 0.01
+	for _, cell := range cells {
+		x, y := cell[0], cell[1]
+		for _, t := range [2][3]int{
+			{at(x-1, y-1), at(x, y-1), at(x-1, y)},
+			{at(x, y-1), at(x, y), at(x-1, y)},
+		} {
+			a, b, c := vertices[t[0]], vertices[t[1]], vertices[t[2]]
+			// The lattice triangles are wound clockwise on the screen, where the y axis points downwards.
+			if (b.X-a.X)*(c.Y-a.Y)-(c.X-a.X)*(b.Y-a.Y) > minArea {
+				triangles = append(triangles, t)
+			}
+		}
+	}
+
+	// The vertices left without a triangle are dropped.
+	index := make([]int, len(vertices))
+	var used []Vec2
+	for i := range index {
+		index[i] = -1
+	}
+	for _, t := range triangles {
+		for _, v := range t {
+			if index[v] < 0 {
+				index[v] = len(used)
+				used = append(used, vertices[v])
+			}
+		}
+	}
+	for i, t := range triangles {
+		triangles[i] = [3]int{index[t[0]], index[t[1]], index[t[2]]}
+	}
+	return NewMeshCloth(used, triangles, col)
+}
@@ -0,0 +1,154 @@
+package physics
+
+import "sync"
+
+// maxColors is the maximum number of independent sets the constraints are partitioned into.
+// The constraints which cannot be put in any of the sets are solved serially.
+const maxColors = 64
+
+// colorSets partitions the constraints of a cloth into sets of independent constraints, where
+// no two constraints of the same set act on the same particle. The constraints of a set can be
+// solved in parallel, since the order they are solved in doesn't change the outcome.
+type colorSets struct {
+	sets    [][]int // the position of the constraints of each set in the constraint store
+	serial  []int   // the constraints which couldn't be assigned to any of the sets
+	layout  int     // the layout of the constraint store the sets were built from
+	lattice [2]bool // whether the shear and bend sticks were enabled when the sets were built
+	built   bool
+}
+
+// build assigns the constraints to the sets with a greedy graph coloring, each constraint
+// getting the first set none of its particles is part of. The coloring depends only on the
+// order of the constraints, so it's always the same for the same cloth. The removed constraints
+// are skipped while solving, so the sets are rebuilt only when the constraints are moved or when
+// the shear and bend sticks are enabled or disabled, the disabled sticks being left out of the sets.
+func (cs *colorSets) build(c *Cloth) {
+	lattice := c.latticeEnabled()
+	if cs.built && cs.layout == c.constraints.layout && cs.lattice == lattice {
+		return
+	}
+	cs.built, cs.layout, cs.lattice = true, c.constraints.layout, lattice
+	params := c.params()
+
+	for i := range cs.sets {
+		cs.sets[i] = cs.sets[i][:0]
+	}
+	cs.serial = cs.serial[:0]
+
+	// The sets each particle is already part of.
+	used := make([]uint64, c.particles.len())
+	for i, ct := range c.constraints.items {
+		if ct == nil {
+			continue
+		}
+		if s, ok := ct.(*stick); ok && s.stiffness(params) == 0 {
+			continue
+		}
+		idx := ct.Indices()
+
+		var mask uint64
+		for _, p := range idx {
+			mask |= used[p]
+		}
+		color := -1
+		for k := 0; k < maxColors; k++ {
+			if mask&(1<<k) == 0 {
+				color = k
+				break
+			}
+		}
+		if color < 0 {
+			cs.serial = append(cs.serial, i)
+			continue
+		}
+		for _, p := range idx {
+			used[p] |= 1 << color
+		}
+		for len(cs.sets) <= color {
+			cs.sets = append(cs.sets, nil)
+		}
+		cs.sets[color] = append(cs.sets[color], i)
+	}
+}
+
+// workerPool runs the tasks on a fixed number of goroutines.
+type workerPool struct {
+	size  int
+	tasks chan func()
+	wg    sync.WaitGroup
+}
+
+// newWorkerPool starts a new pool with size goroutines.
+func newWorkerPool(size int) *workerPool {
+	pool := &workerPool{size: size, tasks: make(chan func())}
+	for i := 0; i < size; i++ {
+		go func() {
+			for task := range pool.tasks {
+				task()
+				pool.wg.Done()
+			}
+		}()
+	}
+	return pool
+}
+
+// run calls fn for each of the n chunks on the pool goroutines and waits for all of them.
+func (pool *workerPool) run(n int, fn func(chunk int)) {
+	pool.wg.Add(n)
+	for i := 0; i < n; i++ {
+		pool.tasks <- func() { fn(i) }
+	}
+	pool.wg.Wait()
+}
+
+// close stops the pool goroutines.
+func (pool *workerPool) close() {
+	close(pool.tasks)
+}
+
+// workers returns the worker pool of the world with the requested
+// number of goroutines, replacing the previous pool if the size has changed.
+func (w *World) workers(size int) *workerPool {
+	if w.pool != nil && w.pool.size != size {
+		w.pool.close()
+		w.pool = nil
+	}
+	if w.pool == nil {
+		w.pool = newWorkerPool(size)
+	}
+	return w.pool
+}
+
+// Close stops the goroutines of the parallel constraint solver. The world can still be
+// stepped after it was closed, the goroutines being started again when they are needed.
+func (w *World) Close() {
+	if w.pool != nil {
+		w.pool.close()
+		w.pool = nil
+	}
+}
+
+// solveParallel relaxes the constraints once, solving the constraints of each independent
+// set across the worker pool. The sets are solved one after the other, in the same order,
+// which keeps the simulation deterministic.
+func (c *Cloth) solveParallel(ctx *Context, pool *workerPool) {
+	items := c.constraints.items
+	solve := func(positions []int) {
+		for _, i := range positions {
+			if ct := items[i]; ct != nil && c.isActive(ct) {
+				ct.Solve(ctx)
+			}
+		}
+	}
+
+	for _, set := range c.colors.sets {
+		chunk := (len(set) + pool.size - 1) / pool.size
+		if chunk == 0 {
+			continue
+		}
+		pool.run((len(set)+chunk-1)/chunk, func(k int) {
+			solve(set[k*chunk : min((k+1)*chunk, len(set))])
+		})
+	}
+	solve(c.colors.serial)
+}
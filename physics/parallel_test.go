@@ -0,0 +1,83 @@
+package physics
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// stepParallel simulates a cloth torn apart by the mouse with the parallel solver,
+// returning the cloth after the provided number of steps and the number of torn constraints.
+func stepParallel(t *testing.T, steps int) (*Cloth, int) {
+	t.Helper()
+
+	c := NewCloth(300, 150, 6, color.NRGBA{})
+	c.PinPattern = PinEveryNth
+	c.PinEvery = 5
+	c.Init(50, 20)
+
+	w := NewWorld(400, 400)
+	p := DefaultParams()
+	p.Workers = 4
+	p.Iterations = 4
+	p.ShearStiffness = 0.5
+	p.BendStiffness = 0.5
+	p.TearStrain = 0.5
+	if err := w.SetParams(p); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Wind = Wind{Direction: 0.3, Strength: 200, GustFrequency: 0.5, GustStrength: 0.5, Turbulence: 0.5}
+	w.Mouse = &Mouse{}
+	w.Add(c)
+
+	var torn int
+	w.Subscribe(func(Event) { torn++ }, EventConstraintBroken)
+
+	for i := 0; i < steps; i++ {
+		// The cloth is dragged downwards through its middle, then released.
+		switch {
+		case i == steps/4:
+			w.Mouse.UpdatePosition(200, 60)
+			w.Mouse.SetLeftButton()
+			w.Mouse.SetDragging(true)
+		case i > steps/4 && i < steps/2:
+			pos := w.Mouse.GetPosition()
+			w.Mouse.UpdatePosition(pos.X, pos.Y+8)
+		case i == steps/2:
+			w.Mouse.ReleaseLeftButton()
+			w.Mouse.SetDragging(false)
+		}
+		w.Step(0.022)
+	}
+	return c, torn
+}
+
+func TestParallelDeterminism(t *testing.T) {
+	const steps = 200
+	a, tornA := stepParallel(t, steps)
+	b, tornB := stepParallel(t, steps)
+
+	if tornA == 0 {
+		t.Fatal("the cloth wasn't torn")
+	}
+	if tornA != tornB {
+		t.Fatalf("got %d and %d torn constraints", tornA, tornB)
+	}
+	pa, pb := a.Particles(), b.Particles()
+	if pa.Len() != pb.Len() {
+		t.Fatalf("got %d and %d particles", pa.Len(), pb.Len())
+	}
+	for i := 0; i < pa.Len(); i++ {
+		u, v := pa.Position(i), pb.Position(i)
+		if math.Float64bits(u.X) != math.Float64bits(v.X) || math.Float64bits(u.Y) != math.Float64bits(v.Y) {
+			t.Fatalf("particle %d: got %v and %v", i, u, v)
+		}
+		if pa.Active(i) != pb.Active(i) {
+			t.Fatalf("particle %d: got active %v and %v", i, pa.Active(i), pb.Active(i))
+		}
+	}
+	if a.constraints.len() != b.constraints.len() {
+		t.Fatalf("got %d and %d constraints", a.constraints.len(), b.constraints.len())
+	}
+}
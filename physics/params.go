@@ -0,0 +1,104 @@
+package physics
+
+import (
+	"fmt"
+	"math"
+)
+
+// Params holds the parameters driving the simulation. It's a plain value,
+// which means it can be copied, compared and set without a running GUI.
+type Params struct {
+	DragForce    float64 // the force applied on the particles dragged by the mouse
+	MaxDragForce float64 // the upper limit of the dragging force increased by holding the mouse button
+	Gravity      float64
+	Stiffness    float64 // limits the velocity transferred from the mouse to the particles
+	Friction     float64 // the amount of velocity kept between two steps
+	DragRadius   float64 // the radius of the area affected by the mouse dragging
+	TearStrain   float64 // the default strain at which the constraints break, zero disables the tearing
+	Iterations   int     // the number of constraint relaxation passes run on each substep
+	Substeps     int     // the number of integration substeps a single step is divided into
+	Workers      int     // the number of goroutines solving the constraints, less than two solves them serially
+	Solver       Solver  // the method the constraints are solved with
+	Compliance   float64 // the compliance of the cloth sticks with the XPBD solver, the inverse of their stiffness
+
+	// The stiffness of the diagonal shear and the skip-one bending constraints
+	// in the [0, 1] range. Setting them to zero disables the constraints.
+	ShearStiffness float64
+	BendStiffness  float64
+
+	// The minimum distance kept between the particles of the same cloth,
+	// which prevents the cloth from passing through itself. Zero disables
+	// the self collision, which is costly for the large cloths.
+	SelfCollision float64
+}
+
+// paramField describes a single simulation parameter and its accepted range.
+type paramField struct {
+	name     string
+	value    float64
+	min, max float64
+}
+
+// DefaultParams returns the parameters the simulation starts with.
+func DefaultParams() Params {
+	return Params{
+		DragForce:    2,
+		MaxDragForce: 15,
+		Gravity:      250,
+		Stiffness:    30,
+		Friction:     0.98,
+		DragRadius:   15,
+		TearStrain:   20,
+		Iterations:   1,
+		Substeps:     1,
+		Workers:      1,
+		Solver:       SolverRelaxation,
+		Compliance:   0.0001,
+	}
+}
+
+// Validate checks if the parameters are inside their accepted range.
+func (p Params) Validate() error {
+	for _, f := range p.fields() {
+		if math.IsNaN(f.value) || f.value < f.min || f.value > f.max {
+			return fmt.Errorf("invalid %s parameter: %g is outside of the [%g, %g] range", f.name, f.value, f.min, f.max)
+		}
+	}
+	if p.DragForce > p.MaxDragForce {
+		return fmt.Errorf("invalid drag force parameter: %g exceeds the maximum drag force %g", p.DragForce, p.MaxDragForce)
+	}
+	return nil
+}
+
+// Diff returns the name of the parameters which are different in p and q.
+func (p Params) Diff(q Params) []string {
+	var diff []string
+
+	pf, qf := p.fields(), q.fields()
+	for i := range pf {
+		if pf[i].value != qf[i].value {
+			diff = append(diff, pf[i].name)
+		}
+	}
+	return diff
+}
+
+func (p Params) fields() []paramField {
+	return []paramField{
+		{name: "drag force", value: p.DragForce, min: 0, max: 100},
+		{name: "max drag force", value: p.MaxDragForce, min: 0, max: 100},
+		{name: "gravity", value: p.Gravity, min: -5000, max: 5000},
+		{name: "stiffness", value: p.Stiffness, min: 0, max: 1000},
+		{name: "friction", value: p.Friction, min: 0, max: 1},
+		{name: "drag radius", value: p.DragRadius, min: 0, max: 1000},
+		{name: "tear strain", value: p.TearStrain, min: 0, max: 1000},
+		{name: "iterations", value: float64(p.Iterations), min: 1, max: 100},
+		{name: "substeps", value: float64(p.Substeps), min: 1, max: 50},
+		{name: "workers", value: float64(p.Workers), min: 0, max: 256},
+		{name: "solver", value: float64(p.Solver), min: float64(SolverRelaxation), max: float64(SolverXPBD)},
+		{name: "compliance", value: p.Compliance, min: 0, max: 1},
+		{name: "shear stiffness", value: p.ShearStiffness, min: 0, max: 1},
+		{name: "bend stiffness", value: p.BendStiffness, min: 0, max: 1},
+		{name: "self collision", value: p.SelfCollision, min: 0, max: 100},
+	}
+}
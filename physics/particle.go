@@ -1,189 +1,294 @@
 package physics
 
 import (
-	"image/color"
 	"math"
 
-	"gioui.org/f32"
-	"gioui.org/layout"
-	"gioui.org/op/clip"
-	"gioui.org/op/paint"
 	"github.com/esimov/cloth-physics/consts"
-	"github.com/esimov/cloth-physics/gui"
 )
 
-// particle holds the basic components of the particle system.
-type particle struct {
-	x, y        float64
-	px, py      float64
-	vx, vy      float64
-	friction    float64
-	stiffness   float64
-	dragForce   float64
-	pinX        bool
-	isActive    bool
-	highlighted bool
-	color       color.NRGBA
-}
-
-// NewParticle initializes a new Particle.
-func NewParticle(x, y float64, hud *gui.Hud, col color.NRGBA) *particle {
-	p := &particle{
-		x: x, y: y, px: x, py: y, color: col,
+// particleFlags holds the boolean state of a particle.
+type particleFlags uint8
+
+const (
+	particleActive particleFlags = 1 << iota
+	particlePinned
+	particleHighlighted // the particle is inside the mouse focus area
+)
+
+// particleStore holds the particles of a body in a structure of arrays layout. Each
+// component of the particles is stored in its own contiguous slice, indexed by the
+// particle index, so the loops running over the particles read only what they need
+// from the memory, without following a pointer for each particle.
+type particleStore struct {
+	pos     []Vec2 // the current positions
+	prev    []Vec2 // the positions from the previous substep, the implicit velocity of the Verlet integrators
+	vel     []Vec2 // the velocities returned by the integrator, with the corrections of the previous substep
+	moved   []Vec2 // the displacements returned by the integrator on the current substep
+	last    []Vec2 // the positions at the beginning of the last step, used for interpolation
+	acc     []Vec2 // the acceleration accumulated until the next integration
+	mass    []float64
+	invMass []float64 // the inverse of the mass, cached for the constraint resolution
+	flags   []particleFlags
+}
+
+// add appends a new active particle at the {x, y} position and returns its index.
+func (s *particleStore) add(x, y float64) int {
+	pos := Vec(x, y)
+	s.pos = append(s.pos, pos)
+	s.prev = append(s.prev, pos)
+	s.vel = append(s.vel, Vec2{})
+	s.moved = append(s.moved, Vec2{})
+	s.last = append(s.last, pos)
+	s.acc = append(s.acc, Vec2{})
+	s.mass = append(s.mass, 1)
+	s.invMass = append(s.invMass, 1)
+	s.flags = append(s.flags, particleActive)
+
+	return len(s.pos) - 1
+}
+
+// len returns the number of particles.
+func (s *particleStore) len() int {
+	return len(s.pos)
+}
+
+// reset removes all the particles.
+func (s *particleStore) reset() {
+	*s = particleStore{}
+}
+
+// compact moves each particle i to the index[i] position, dropping the particles with
+// a negative index. The new indices should keep the order of the particles.
+func (s *particleStore) compact(index []int) {
+	var n int
+	for i, j := range index {
+		if j < 0 {
+			continue
+		}
+		s.pos[j], s.prev[j], s.vel[j], s.moved[j] = s.pos[i], s.prev[i], s.vel[i], s.moved[i]
+		s.last[j], s.acc[j] = s.last[i], s.acc[i]
+		s.mass[j], s.invMass[j], s.flags[j] = s.mass[i], s.invMass[i], s.flags[i]
+		n++
 	}
-	hudDragForce := float64(hud.Sliders[gui.HudSliderDragForce].Widget.Value)
-	hudStiffness := float64(hud.Sliders[gui.HudSliderStiffness].Widget.Value)
+	s.pos, s.prev, s.vel, s.moved = s.pos[:n], s.prev[:n], s.vel[:n], s.moved[:n]
+	s.last, s.acc = s.last[:n], s.acc[:n]
+	s.mass, s.invMass, s.flags = s.mass[:n], s.invMass[:n], s.flags[:n]
+}
 
-	p.isActive = true
-	p.highlighted = false
-	p.dragForce = hudDragForce
-	p.stiffness = hudStiffness
+// is reports if the particle i has the flag f set.
+func (s *particleStore) is(i int, f particleFlags) bool {
+	return s.flags[i]&f != 0
+}
 
-	return p
+// set sets or clears the flag f of the particle i.
+func (s *particleStore) set(i int, f particleFlags, on bool) {
+	if on {
+		s.flags[i] |= f
+	} else {
+		s.flags[i] &^= f
+	}
 }
 
-// Update updates the particle system using the Verlet integration.
-func (p *particle) Update(gtx layout.Context, mouse *Mouse, hud *gui.Hud, delta float64) {
-	p.update(gtx, mouse, hud, delta)
+// active reports if the particle i is still part of the simulation.
+func (s *particleStore) active(i int) bool {
+	return s.is(i, particleActive)
 }
 
-// Deprecated: draw draws the particle at the {x, y} position with the radius `r`.
-func (p *particle) draw(gtx layout.Context, x, y, r float32) {
-	var (
-		sq   float64
-		p1   f32.Point
-		p2   f32.Point
-		orig = f32.Pt(x-r, y)
-	)
+// pinned reports if the particle i is pinned.
+func (s *particleStore) pinned(i int) bool {
+	return s.is(i, particlePinned)
+}
 
-	sq = math.Sqrt(float64(r*r) - float64(r*r))
-	p1 = f32.Pt(x+float32(sq), y).Sub(orig)
-	p2 = f32.Pt(x-float32(sq), y).Sub(orig)
+// free reports if the particle i is active and not pinned, which means that it's moving.
+func (s *particleStore) free(i int) bool {
+	return s.flags[i]&(particleActive|particlePinned) == particleActive
+}
 
-	var path clip.Path
-	path.Begin(gtx.Ops)
-	path.Move(orig)
-	path.Arc(p1, p2, 2*math.Pi)
-	path.Close()
+// pin pins or releases the particle i. The particle is stopped in both cases, otherwise
+// the position it had before being pinned would be taken as its velocity on release.
+func (s *particleStore) pin(i int, pinned bool) {
+	s.set(i, particlePinned, pinned)
+	s.prev[i] = s.pos[i]
+	s.vel[i] = Vec2{}
+}
 
-	defer clip.Outline{Path: path.End()}.Op().Push(gtx.Ops).Pop()
-	paint.ColorOp{Color: p.color}.Add(gtx.Ops)
-	paint.PaintOp{}.Add(gtx.Ops)
+// setMass sets the mass of the particle i together with its inverse.
+func (s *particleStore) setMass(i int, mass float64) {
+	s.mass[i] = mass
+	s.invMass[i] = 1 / mass
 }
 
-// update is an internal method to update the cloth system using Verlet integration.
-func (p *particle) update(gtx layout.Context, mouse *Mouse, hud *gui.Hud, dt float64) {
-	p.highlighted = false
+// weight returns the inverse mass of the particle i used for weighting the constraint
+// corrections. The pinned particles behave as if they would have an infinite mass.
+func (s *particleStore) weight(i int) float64 {
+	if s.pinned(i) {
+		return 0
+	}
+	return s.invMass[i]
+}
 
-	p.dragForce = float64(hud.Sliders[gui.HudSliderDragForce].Widget.Value)
-	gravityForce := float64(hud.Sliders[gui.HudSliderGravityForce].Widget.Value)
-	p.stiffness = float64(hud.Sliders[gui.HudSliderStiffness].Widget.Value)
+// interpolate returns the position of the particle i between the last two steps.
+func (s *particleStore) interpolate(i int, alpha float64) Vec2 {
+	last := s.last[i]
+	return last.Add(s.pos[i].Sub(last).Mul(alpha))
+}
 
-	p.friction = float64(hud.Sliders[gui.HudSliderFriction].Widget.Value)
-	tearDistance := float64(hud.Sliders[gui.HudSliderTearDistance].Widget.Value)
+// integration holds the values the particles are integrated with on a substep.
+type integration struct {
+	mouse      *Mouse
+	params     Params
+	integrator Integrator
+	timestep   Timestep
+	substeps   int
+	dragForce  float64
+	focusArea  float64
+}
 
-	if p.pinX {
-		// Recalculate the pinned particles position when the window is resized.
-		// We need to do this only for the pinned particles, because the rest
-		// of the particles will just adjust themselves automatically.
-		p.x += hud.WinOffsetX
-		p.y += hud.WinOffsetY
-		return
+// newIntegration prepares the integration of a substep out of the substeps a step is divided into.
+// The prevDt is the duration of the previous substep, used by the time-corrected integrators.
+func newIntegration(mouse *Mouse, params Params, integrator Integrator, dt, prevDt float64, substeps int) *integration {
+	in := &integration{
+		mouse:      mouse,
+		params:     params,
+		integrator: integrator,
+		timestep: Timestep{
+			Dt:     dt,
+			PrevDt: prevDt,
+			// The friction is applied on each substep, so it has to be scaled down
+			// to keep the same amount of velocity over the whole step.
+			Damping: math.Pow(params.Friction, 1/float64(substeps)),
+		},
+		substeps:  substeps,
+		dragForce: params.DragForce,
 	}
 
 	// Holding the left mouse button will increase the dragging force
 	// resulting in a much advanced cloth destruction.
 	if mouse.GetLeftButton() {
-		maxDragForce := float64(hud.Sliders[gui.HudSliderDragForce].Max)
-		p.increaseForce(mouse, maxDragForce)
+		in.dragForce = min(in.dragForce+mouse.GetForce(), params.MaxDragForce)
 	}
 
-	// Window width and height.
-	width, height := gtx.Constraints.Max.X, gtx.Constraints.Max.Y
+	// Modify the mouse focus area size on scrolling.
+	in.focusArea = mouse.GetScrollY()
+	if in.focusArea > consts.MaxFocusArea {
+		in.focusArea = consts.MaxFocusArea
+	} else if in.focusArea < consts.MinFocusArea {
+		in.focusArea = consts.MinFocusArea
+	}
+	return in
+}
+
+// update is an internal method to update the particle i using the integrator of the world.
+func (s *particleStore) update(i int, in *integration) {
+	s.flags[i] &^= particleHighlighted
+
+	if s.pinned(i) {
+		return
+	}
+	mouse, stiffness := in.mouse, in.params.Stiffness
 
-	dx := p.x - mouse.x
-	dy := p.y - mouse.y
+	pos := s.pos[i]
+	dx := pos.X - mouse.x
+	dy := pos.Y - mouse.y
 	dist := math.Sqrt(dx*dx + dy*dy)
 
-	if mouse.GetDragging() && dist < float64(tearDistance) {
-		dx := mouse.x - mouse.px
-		dy := mouse.y - mouse.py
-		if dx > p.stiffness {
-			dx = p.stiffness
-		}
-		if dy > p.stiffness {
-			dy = p.stiffness
-		}
-		if dx < -p.stiffness {
-			dx = -p.stiffness
-		}
-		if dy < -p.stiffness {
-			dy = -p.stiffness
-		}
-		p.px = p.x - dx*p.dragForce
-		p.py = p.y - dy*p.dragForce
+	if mouse.GetDragging() && dist < in.params.DragRadius {
+		dx := math.Max(-stiffness, math.Min(mouse.x-mouse.px, stiffness))
+		dy := math.Max(-stiffness, math.Min(mouse.y-mouse.py, stiffness))
+
+		// The mouse movement is distributed over the substeps.
+		drag := Vec(dx, dy).Mul(in.dragForce / float64(in.substeps))
+		s.prev[i] = pos.Sub(drag)
+		s.vel[i] = drag.Mul(1 / in.timestep.Dt)
 	}
 
 	// Pin up the particle if the mouse is pressed combined with the CTRL key.
 	if mouse.GetCtrlDown() && dist < consts.ClothPinDist {
-		p.pinX = true
+		s.pin(i, true)
 	}
 
-	// Modify the mouse focus area size on scrolling.
-	focusArea := mouse.GetScrollY()
-	if focusArea > consts.MaxFocusArea {
-		focusArea = consts.MaxFocusArea
-	} else if focusArea < consts.MinFocusArea {
-		focusArea = consts.MinFocusArea
-	}
+	if dist < in.focusArea {
+		s.flags[i] |= particleHighlighted
 
-	if dist < float64(focusArea) {
-		p.highlighted = true
-	}
-
-	// With right click we can tear up the cloth at the mouse position.
-	if mouse.GetRightButton() {
-		if dist < float64(focusArea) {
-			p.isActive = false
+		// With right click we can tear up the cloth at the mouse position.
+		if mouse.GetRightButton() {
+			s.flags[i] &^= particleActive
 		}
 	}
 
-	px, py := p.x, p.y
-	p.vy += gravityForce
+	acc := s.acc[i]
+	acc.Y += in.params.Gravity
 
-	// position = velocity * deltaTime
-	posX, posY := p.vx*(dt*dt), p.vy*(dt*dt)
+	p := in.integrator.Integrate(ParticleState{Pos: pos, Prev: s.prev[i], Vel: s.vel[i]}, acc, in.timestep)
+	s.pos[i], s.prev[i], s.vel[i], s.moved[i] = p.Pos, p.Prev, p.Vel, p.Pos.Sub(p.Prev)
+	s.acc[i] = Vec2{}
+}
 
-	// Verlet integration:
-	// x(t+Δt)=2x(t)−x(t−Δt)+a(t)Δt2
-	p.x = p.x + (p.x-p.px)*p.friction + posX
-	p.y = p.y + (p.y-p.py)*p.friction + posY
+// Particles provides indexed access to the particles of a body for the constraints.
+type Particles struct {
+	s *particleStore
+}
 
-	p.px, p.py = px, py
+// Len returns the number of particles.
+func (ps Particles) Len() int {
+	return ps.s.len()
+}
 
-	if p.x >= float64(width) {
-		p.x = float64(width)
-		p.px = p.x
-	} else if p.x < 0 {
-		p.x = 0
-		p.px = p.x
-	}
+// Position returns the current position of the particle i.
+func (ps Particles) Position(i int) Vec2 {
+	return ps.s.pos[i]
+}
 
-	if p.y > float64(height) {
-		p.y = float64(height)
-		p.py = p.y
-	} else if p.y < 0 {
-		p.y = 0
-		p.py = p.y
-	}
+// SetPosition moves the particle i to a new position.
+func (ps Particles) SetPosition(i int, v Vec2) {
+	ps.s.pos[i] = v
+}
+
+// Previous returns the position of the particle i from the previous substep.
+func (ps Particles) Previous(i int) Vec2 {
+	return ps.s.prev[i]
+}
+
+// Velocity returns the velocity of the particle i at the end of the last substep.
+func (ps Particles) Velocity(i int) Vec2 {
+	return ps.s.vel[i]
+}
+
+// Mass returns the mass of the particle i.
+func (ps Particles) Mass(i int) float64 {
+	return ps.s.mass[i]
+}
 
-	p.vx, p.vy = 0.0, 0.0
+// InvMass returns the inverse mass of the particle i, which is zero for the pinned particles.
+func (ps Particles) InvMass(i int) float64 {
+	return ps.s.weight(i)
 }
 
-// increaseForce increases the dragging force.
-func (p *particle) increaseForce(m *Mouse, maxDragForce float64) {
-	p.dragForce += m.GetForce()
-	if p.dragForce > maxDragForce {
-		p.dragForce = maxDragForce
+// Pinned reports if the particle i is pinned.
+func (ps Particles) Pinned(i int) bool {
+	return ps.s.pinned(i)
+}
+
+// Active reports if the particle i is still part of the simulation.
+func (ps Particles) Active(i int) bool {
+	return ps.s.active(i)
+}
+
+// applyCorrection moves the particle a by the correction d and the particle b by -d.
+// The correction is distributed between the particles proportionally to their inverse
+// mass, so the heavier particle moves less, while the pinned particles don't move at all.
+func (ps Particles) applyCorrection(a, b int, d Vec2) {
+	wa, wb := ps.s.weight(a), ps.s.weight(b)
+	if wa+wb == 0 {
+		return
 	}
+	ps.s.pos[a] = ps.s.pos[a].Add(d.Mul(wa / (wa + wb)))
+	ps.s.pos[b] = ps.s.pos[b].Sub(d.Mul(wb / (wa + wb)))
+}
+
+// applyForce accelerates the particle a by the force f and the particle b by -f,
+// scaled by their inverse mass. The acceleration is applied on the next integration.
+func (ps Particles) applyForce(a, b int, f Vec2) {
+	ps.s.acc[a] = ps.s.acc[a].Add(f.Mul(ps.s.weight(a)))
+	ps.s.acc[b] = ps.s.acc[b].Sub(f.Mul(ps.s.weight(b)))
 }
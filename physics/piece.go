@@ -0,0 +1,161 @@
+package physics
+
+import (
+	"math"
+	"slices"
+)
+
+// Piece is a connected part of a cloth. The particles of a piece are linked to each
+// other by the constraints, directly or through the other particles of the piece.
+type Piece struct {
+	Particles []int   // the index of the particles belonging to the piece
+	Area      float64 // the area of the triangles inside the piece
+	Min, Max  Vec2    // the corners of the bounding box
+	Pinned    bool    // reports whether the piece is held by at least one pinned particle
+}
+
+// pieceTracker keeps track of the connected components of the cloth. The components
+// are found with a union-find over the active constraints, which is only rebuilt when
+// the constraints or the active particles of the cloth have changed.
+type pieceTracker struct {
+	parent  []int
+	label   []int   // the piece of each particle, -1 for the inactive particles
+	members [][]int // the particles of each piece
+	version int     // the version of the constraint store the pieces were built from
+	lattice [2]bool // whether the shear and bend sticks were enabled when the pieces were built
+	dirty   bool
+}
+
+// find returns the root of the set containing i, halving the path on the way.
+func (t *pieceTracker) find(i int) int {
+	for t.parent[i] != i {
+		t.parent[i] = t.parent[t.parent[i]]
+		i = t.parent[i]
+	}
+	return i
+}
+
+// union merges the sets containing a and b.
+func (t *pieceTracker) union(a, b int) {
+	ra, rb := t.find(a), t.find(b)
+	if ra != rb {
+		t.parent[max(ra, rb)] = min(ra, rb)
+	}
+}
+
+// invalidate marks the pieces as outdated.
+func (t *pieceTracker) invalidate() {
+	t.dirty = true
+}
+
+// outdated reports if the pieces have to be rebuilt after the cloth has changed.
+func (t *pieceTracker) outdated(c *Cloth) bool {
+	return t.dirty || t.version != c.constraints.version || len(t.label) != c.particles.len() ||
+		t.lattice != c.latticeEnabled()
+}
+
+// latticeEnabled reports whether the shear and bend sticks are enabled by the simulation parameters.
+func (c *Cloth) latticeEnabled() [2]bool {
+	params := c.params()
+	return [2]bool{params.ShearStiffness != 0, params.BendStiffness != 0}
+}
+
+// updatePieces rebuilds the pieces of the cloth if they are outdated.
+func (c *Cloth) updatePieces() {
+	t := &c.pieces
+	if !t.outdated(c) {
+		return
+	}
+	t.dirty = false
+	t.version = c.constraints.version
+	t.lattice = c.latticeEnabled()
+	params := c.params()
+
+	n := c.particles.len()
+	t.parent = t.parent[:0]
+	for i := 0; i < n; i++ {
+		t.parent = append(t.parent, i)
+	}
+	for _, ct := range c.constraints.items {
+		if ct == nil || !c.isActive(ct) {
+			continue
+		}
+		// The disabled lattice sticks don't hold the particles together.
+		if s, ok := ct.(*stick); ok && s.stiffness(params) == 0 {
+			continue
+		}
+		idx := ct.Indices()
+		for _, i := range idx[min(1, len(idx)):] {
+			t.union(idx[0], i)
+		}
+	}
+
+	// The roots are numbered in the order of their first particle,
+	// so the pieces are listed in a stable order between the updates.
+	t.label = t.label[:0]
+	t.members = t.members[:0]
+	roots := make(map[int]int)
+	for i := 0; i < n; i++ {
+		if !c.particles.active(i) {
+			t.label = append(t.label, -1)
+			continue
+		}
+		r := t.find(i)
+		piece, ok := roots[r]
+		if !ok {
+			piece = len(t.members)
+			roots[r] = piece
+			t.members = append(t.members, nil)
+		}
+		t.label = append(t.label, piece)
+		t.members[piece] = append(t.members[piece], i)
+	}
+}
+
+// Pieces returns the connected pieces the cloth is torn into. An intact cloth consists of
+// a single piece, and the pieces are recomputed only after the cloth has been torn apart.
+// The area, the bounding box and the pinned status reflect the current state of the cloth.
+// The particle slices are copies, so they can be kept and modified by the caller.
+func (c *Cloth) Pieces() []Piece {
+	c.updatePieces()
+
+	t := &c.pieces
+	pieces := make([]Piece, len(t.members))
+	for i, members := range t.members {
+		piece := Piece{
+			Particles: slices.Clone(members),
+			Min:       Vec(math.Inf(1), math.Inf(1)),
+			Max:       Vec(math.Inf(-1), math.Inf(-1)),
+		}
+		for _, idx := range members {
+			p := c.particles.pos[idx]
+			piece.Min = Vec(math.Min(piece.Min.X, p.X), math.Min(piece.Min.Y, p.Y))
+			piece.Max = Vec(math.Max(piece.Max.X, p.X), math.Max(piece.Max.Y, p.Y))
+			piece.Pinned = piece.Pinned || c.particles.pinned(idx)
+		}
+		pieces[i] = piece
+	}
+
+	// The triangles are counted only if all their vertices belong to the same piece.
+	for _, tri := range c.triangles {
+		piece := t.label[tri[0]]
+		if piece < 0 || t.label[tri[1]] != piece || t.label[tri[2]] != piece {
+			continue
+		}
+		a, b, d := c.particles.pos[tri[0]], c.particles.pos[tri[1]], c.particles.pos[tri[2]]
+		pieces[piece].Area += math.Abs((b.X-a.X)*(d.Y-a.Y)-(d.X-a.X)*(b.Y-a.Y)) / 2
+	}
+	return pieces
+}
+
+// RemovePiece deactivates all the particles of the piece, which can be used for
+// removing the free falling pieces. The particles are dropped on the next compaction.
+func (c *Cloth) RemovePiece(piece Piece) {
+	for _, i := range piece.Particles {
+		if c.particles.active(i) {
+			c.particles.set(i, particleActive, false)
+			c.emitParticle(EventParticleDeactivated, i)
+		}
+	}
+	c.pieces.invalidate()
+}
@@ -0,0 +1,164 @@
+package physics
+
+// PinPattern selects which particles of the cloth grid are pinned on initialization.
+type PinPattern int
+
+const (
+	// PinEveryNth pins every Nth particle of the top row, N being defined by Cloth.PinEvery.
+	PinEveryNth PinPattern = iota
+	// PinTopRow pins all the particles of the top row.
+	PinTopRow
+	// PinCorners pins the top-left and the top-right corner of the cloth.
+	PinCorners
+	// PinLeftEdge pins the left column of the cloth, like a flag attached to a pole.
+	PinLeftEdge
+	// PinCustom pins the particles listed in Cloth.PinIndices.
+	PinCustom
+)
+
+// String returns the human readable name of the pin pattern.
+func (p PinPattern) String() string {
+	switch p {
+	case PinEveryNth:
+		return "Every Nth"
+	case PinTopRow:
+		return "Top row"
+	case PinCorners:
+		return "Corners"
+	case PinLeftEdge:
+		return "Left edge"
+	case PinCustom:
+		return "Custom"
+	default:
+		return "Unknown"
+	}
+}
+
+// isPinned reports if the pattern selects the particle from the {x, y} grid position.
+func (c *Cloth) isPinned(x, y int) bool {
+	last := c.cols - 1
+
+	switch c.PinPattern {
+	case PinEveryNth:
+		n := c.PinEvery
+		if n <= 0 {
+			// Pin by default ten particles, but with less than ten columns every
+			// particle of the top row gets pinned instead of dividing by zero.
+			n = max(last/10, 1)
+		}
+		return y == 0 && x%n == 0
+	case PinTopRow:
+		return y == 0
+	case PinCorners:
+		return y == 0 && (x == 0 || x == last)
+	case PinLeftEdge:
+		return x == 0
+	case PinCustom:
+		return c.isListed(x + y*c.cols)
+	}
+	return false
+}
+
+// isListed reports if the index is listed in the PinIndices.
+func (c *Cloth) isListed(idx int) bool {
+	for _, i := range c.PinIndices {
+		if i == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// Repin unpins all the particles, then pins the ones selected by the pin pattern.
+// The pinned particles are kept at their current position.
+func (c *Cloth) Repin() {
+	// The custom bodies have no grid the pattern could be applied on,
+	// while the mesh cloths can only be pinned by their vertex index.
+	if c.cols == 0 {
+		if c.mesh != nil && c.PinPattern == PinCustom {
+			for v, idx := range c.grid {
+				if idx >= 0 {
+					c.setPin(idx, c.isListed(v))
+				}
+			}
+		}
+		return
+	}
+	for i, idx := range c.grid {
+		if idx >= 0 {
+			c.setPin(idx, c.isPinned(i%c.cols, i/c.cols))
+		}
+	}
+}
+
+// Index returns the index of the particle from the {col, row} grid position. The second
+// return value reports whether the position is inside the grid and its particle exists,
+// which is not the case for the particles removed by the compaction.
+func (c *Cloth) Index(col, row int) (int, bool) {
+	if col < 0 || row < 0 || col >= c.cols || row >= c.rows {
+		return 0, false
+	}
+	idx := c.grid[col+row*c.cols]
+	return idx, idx >= 0
+}
+
+// Vertex returns the index of the particle built from the vertex v of a mesh cloth.
+// The second return value reports whether the cloth has such a vertex and its particle
+// exists, which is not the case for the particles removed by the compaction.
+func (c *Cloth) Vertex(v int) (int, bool) {
+	if c.mesh == nil || v < 0 || v >= len(c.grid) {
+		return 0, false
+	}
+	idx := c.grid[v]
+	return idx, idx >= 0
+}
+
+// Pin pins the particle i at its current position.
+func (c *Cloth) Pin(i int) {
+	c.setPin(i, true)
+}
+
+// Unpin releases the pinned particle i.
+func (c *Cloth) Unpin(i int) {
+	c.setPin(i, false)
+}
+
+// setPin changes the pinned state of the particle i, emitting an event if the state has changed.
+func (c *Cloth) setPin(i int, pinned bool) {
+	if c.particles.pinned(i) == pinned {
+		return
+	}
+	c.particles.pin(i, pinned)
+	if pinned {
+		c.emitParticle(EventParticlePinned, i)
+	} else {
+		c.emitParticle(EventParticleUnpinned, i)
+	}
+}
+
+// PinAt pins the active particles found inside the circle with the provided center
+// and radius, and returns the number of the pinned particles.
+func (c *Cloth) PinAt(pos Vec2, radius float64) int {
+	return c.setPinned(pos, radius, true)
+}
+
+// UnpinAt releases the pinned particles found inside the circle with the provided
+// center and radius, and returns the number of the released particles.
+func (c *Cloth) UnpinAt(pos Vec2, radius float64) int {
+	return c.setPinned(pos, radius, false)
+}
+
+func (c *Cloth) setPinned(pos Vec2, radius float64, pinned bool) int {
+	var count int
+	ps := &c.particles
+	for i, p := range ps.pos {
+		if !ps.active(i) || ps.pinned(i) == pinned {
+			continue
+		}
+		if p.Sub(pos).Len() <= radius {
+			c.setPin(i, pinned)
+			count++
+		}
+	}
+	return count
+}
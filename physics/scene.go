@@ -0,0 +1,89 @@
+package physics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Scene describes the static content of a world, loaded from a JSON scene file like:
+//
+//	{
+//		"obstacles": [
+//			{"type": "circle", "center": {"x": 500, "y": 400}, "radius": 60, "friction": 0.3},
+//			{"type": "box", "min": {"x": 100, "y": 500}, "max": {"x": 300, "y": 540}},
+//			{"type": "capsule", "a": {"x": 600, "y": 500}, "b": {"x": 800, "y": 450}, "radius": 15},
+//			{"type": "polygon", "points": [{"x": 850, "y": 600}, {"x": 950, "y": 600}, {"x": 900, "y": 520}]}
+//		]
+//	}
+type Scene struct {
+	Obstacles []ObstacleSpec `json:"obstacles"`
+}
+
+// ObstacleSpec is the description of a single obstacle in the scene file.
+// The fields used depend on the obstacle type.
+type ObstacleSpec struct {
+	Type     string  `json:"type"`
+	Friction float64 `json:"friction"`
+	Center   Vec2    `json:"center"`
+	Radius   float64 `json:"radius"`
+	Min      Vec2    `json:"min"`
+	Max      Vec2    `json:"max"`
+	A        Vec2    `json:"a"`
+	B        Vec2    `json:"b"`
+	Points   []Vec2  `json:"points"`
+}
+
+// Obstacle converts the description into an obstacle. It fails if the description is invalid,
+// like a shape without a radius, an inverted box or a concave polygon.
+func (s ObstacleSpec) Obstacle() (Obstacle, error) {
+	if !(s.Friction >= 0 && s.Friction <= 1) {
+		return Obstacle{}, fmt.Errorf("invalid obstacle friction: %g", s.Friction)
+	}
+	o := Obstacle{Friction: s.Friction}
+
+	switch s.Type {
+	case "circle", "capsule":
+		if !(s.Radius > 0) {
+			return Obstacle{}, fmt.Errorf("invalid %s radius: %g", s.Type, s.Radius)
+		}
+		if s.Type == "circle" {
+			o.Shape = Circle{Center: s.Center, Radius: s.Radius}
+		} else {
+			o.Shape = Capsule{A: s.A, B: s.B, Radius: s.Radius}
+		}
+	case "box":
+		if s.Min.X > s.Max.X || s.Min.Y > s.Max.Y {
+			return Obstacle{}, fmt.Errorf("invalid box: the min corner %v is past the max corner %v", s.Min, s.Max)
+		}
+		o.Shape = Box{Min: s.Min, Max: s.Max}
+	case "polygon":
+		if len(s.Points) < 3 {
+			return Obstacle{}, fmt.Errorf("the polygon obstacle should have at least 3 points, got %d", len(s.Points))
+		}
+		if !convex(s.Points) {
+			return Obstacle{}, fmt.Errorf("the polygon obstacle should be convex")
+		}
+		o.Shape = Polygon{Points: s.Points}
+	default:
+		return Obstacle{}, fmt.Errorf("unknown obstacle type: %q", s.Type)
+	}
+	return o, nil
+}
+
+// LoadScene decodes the scene from r and returns the obstacles described in it.
+func LoadScene(r io.Reader) ([]Obstacle, error) {
+	var scene Scene
+	if err := json.NewDecoder(r).Decode(&scene); err != nil {
+		return nil, fmt.Errorf("failed to decode the scene: %w", err)
+	}
+	obstacles := make([]Obstacle, 0, len(scene.Obstacles))
+	for i, spec := range scene.Obstacles {
+		o, err := spec.Obstacle()
+		if err != nil {
+			return nil, fmt.Errorf("obstacle %d: %w", i, err)
+		}
+		obstacles = append(obstacles, o)
+	}
+	return obstacles, nil
+}
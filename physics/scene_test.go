@@ -0,0 +1,35 @@
+package physics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestObstacleSpec(t *testing.T) {
+	square := []Vec2{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	tests := []struct {
+		name  string
+		spec  ObstacleSpec
+		valid bool
+	}{
+		{"circle", ObstacleSpec{Type: "circle", Radius: 10}, true},
+		{"circle without radius", ObstacleSpec{Type: "circle"}, false},
+		{"capsule with negative radius", ObstacleSpec{Type: "capsule", B: Vec(10, 0), Radius: -1}, false},
+		{"box", ObstacleSpec{Type: "box", Max: Vec(10, 10)}, true},
+		{"inverted box", ObstacleSpec{Type: "box", Min: Vec(10, 0), Max: Vec(0, 10)}, false},
+		{"square", ObstacleSpec{Type: "polygon", Points: square}, true},
+		{"reversed square", ObstacleSpec{Type: "polygon", Points: []Vec2{{0, 10}, {10, 10}, {10, 0}, {0, 0}}}, true},
+		{"collinear points", ObstacleSpec{Type: "polygon", Points: []Vec2{{0, 0}, {5, 0}, {10, 0}, {10, 10}}}, true},
+		{"concave polygon", ObstacleSpec{Type: "polygon", Points: []Vec2{{0, 0}, {10, 0}, {5, 3}, {10, 10}, {0, 10}}}, false},
+		{"pentagram", ObstacleSpec{Type: "polygon", Points: []Vec2{{0, -10}, {6, 8}, {-10, -3}, {10, -3}, {-6, 8}}}, false},
+		{"flat polygon", ObstacleSpec{Type: "polygon", Points: []Vec2{{0, 0}, {5, 0}, {10, 0}}}, false},
+		{"too few points", ObstacleSpec{Type: "polygon", Points: square[:2]}, false},
+		{"invalid friction", ObstacleSpec{Type: "circle", Radius: 10, Friction: math.NaN()}, false},
+		{"unknown type", ObstacleSpec{Type: "star"}, false},
+	}
+	for _, tt := range tests {
+		if _, err := tt.spec.Obstacle(); (err == nil) != tt.valid {
+			t.Errorf("%s: got error %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package physics
+
+import "math"
+
+// spatialHash is a uniform grid hashing the particle positions into a fixed size table.
+// The particles of a cell are stored contiguously in the entries slice, starting at the
+// index found in the cells table. Different grid cells can share the same table slot,
+// so the queried particles have to be filtered by their distance.
+type spatialHash struct {
+	spacing float64
+	cells   []int // the start of each table slot in the entries, with an extra sentinel at the end
+	entries []int
+}
+
+// build hashes the active particles into a grid with the provided cell size.
+func (h *spatialHash) build(ps *particleStore, spacing float64) {
+	h.spacing = spacing
+	size := 2*ps.len() + 1
+
+	if cap(h.cells) < size+1 {
+		h.cells = make([]int, size+1)
+	}
+	h.cells = h.cells[:size+1]
+	clear(h.cells)
+
+	// Count the particles of each slot, then turn the counts into end indices.
+	// Filling the entries backwards moves them to the start of their slot.
+	for i, p := range ps.pos {
+		if ps.active(i) {
+			h.cells[h.slot(p.X, p.Y)]++
+		}
+	}
+	for i := 1; i <= size; i++ {
+		h.cells[i] += h.cells[i-1]
+	}
+	h.entries = h.entries[:0]
+	h.entries = append(h.entries, make([]int, h.cells[size])...)
+
+	for i, p := range ps.pos {
+		if ps.active(i) {
+			s := h.slot(p.X, p.Y)
+			h.cells[s]--
+			h.entries[h.cells[s]] = i
+		}
+	}
+}
+
+// slot returns the table slot of the grid cell containing the {x, y} position.
+func (h *spatialHash) slot(x, y float64) int {
+	return h.cellSlot(int(math.Floor(x/h.spacing)), int(math.Floor(y/h.spacing)))
+}
+
+// cellSlot returns the table slot of the {cx, cy} grid cell.
+func (h *spatialHash) cellSlot(cx, cy int) int {
+	n := uint64(len(h.cells) - 1)
+	return int((uint64(cx)*92837111 ^ uint64(cy)*689287499) % n)
+}
+
+// query calls fn for each particle hashed in the grid cells around the {x, y} position.
+func (h *spatialHash) query(x, y float64, fn func(i int)) {
+	cx, cy := int(math.Floor(x/h.spacing)), int(math.Floor(y/h.spacing))
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			s := h.cellSlot(cx+dx, cy+dy)
+			for _, i := range h.entries[h.cells[s]:h.cells[s+1]] {
+				fn(i)
+			}
+		}
+	}
+}
+
+// collideSelf pushes apart the particles of the cloth which are closer to each other than
+// the minimum distance. Each pair is moved proportionally to the inverse mass of the particles.
+func (c *Cloth) collideSelf(dist float64) {
+	ps := &c.particles
+	for i, p := range ps.pos {
+		if !ps.active(i) {
+			continue
+		}
+		c.hash.query(p.X, p.Y, func(j int) {
+			// Each pair is resolved only once.
+			if j <= i {
+				return
+			}
+			p, q := ps.pos[i], ps.pos[j]
+			delta := q.Sub(p)
+			d2 := delta.Dot(delta)
+			if d2 >= dist*dist || d2 == 0 {
+				return
+			}
+			w1, w2 := ps.weight(i), ps.weight(j)
+			if w1+w2 == 0 {
+				return
+			}
+			d := math.Sqrt(d2)
+			corr := delta.Mul((dist - d) / (d * (w1 + w2)))
+			ps.pos[i] = p.Sub(corr.Mul(w1))
+			ps.pos[j] = q.Add(corr.Mul(w2))
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package physics
+
+// constraintStore holds the constraints of a body. The removed constraints are replaced
+// by tombstones, which means that a constraint can be removed in constant time, even while
+// the store is iterated. The tombstones are dropped later, when the store is compacted.
+//
+// The lattice sticks keep their position in the items themselves, while the position of the
+// rest of the constraints is looked up by the constraint, so the stored constraints have to be
+// comparable, which is the case for the pointer types.
+type constraintStore struct {
+	items []Constraint       // the stored constraints, with nil tombstones in place of the removed ones
+	index map[Constraint]int // the position of each live constraint in the items, except the sticks
+	dead  int                // the number of tombstones
+
+	// version is increased on each change of the stored constraints, while layout only
+	// when the constraints are moved to a new position, which lets the derived data to
+	// detect when it has to be updated.
+	version int
+	layout  int
+}
+
+// add appends a new constraint to the store.
+func (s *constraintStore) add(c Constraint) {
+	if st, ok := c.(*stick); ok {
+		st.removed, st.slot = false, len(s.items)
+	} else {
+		if s.index == nil {
+			s.index = make(map[Constraint]int)
+		}
+		s.index[c] = len(s.items)
+	}
+	s.items = append(s.items, c)
+	s.version++
+	s.layout++
+}
+
+// find returns the position of the constraint in the items. It reports whether the constraint was found.
+func (s *constraintStore) find(c Constraint) (int, bool) {
+	if st, ok := c.(*stick); ok {
+		// The stick could be part of another body, or it could be removed already.
+		if st.slot < len(s.items) && s.items[st.slot] == c {
+			return st.slot, true
+		}
+		return 0, false
+	}
+	i, ok := s.index[c]
+	return i, ok
+}
+
+// remove replaces the constraint with a tombstone. It reports whether the constraint was found.
+func (s *constraintStore) remove(c Constraint) bool {
+	i, ok := s.find(c)
+	if ok {
+		s.removeAt(i)
+	}
+	return ok
+}
+
+// removeAt replaces the constraint found at position i with a tombstone.
+func (s *constraintStore) removeAt(i int) {
+	if s.items[i] == nil {
+		return
+	}
+	// The lattice sticks keep track of their removal, since the
+	// shear and bend sticks depend on the structural ones.
+	if st, ok := s.items[i].(*stick); ok {
+		st.removed = true
+	} else {
+		delete(s.index, s.items[i])
+	}
+	s.items[i] = nil
+	s.dead++
+	s.version++
+}
+
+// len returns the number of live constraints.
+func (s *constraintStore) len() int {
+	return len(s.items) - s.dead
+}
+
+// tidy compacts the store once the tombstones make up a quarter of it, which spreads
+// the cost of the compaction over many removals.
+func (s *constraintStore) tidy() {
+	if s.dead > len(s.items)/4 {
+		s.compact()
+	}
+}
+
+// compact drops the tombstones, keeping the order of the live constraints.
+func (s *constraintStore) compact() {
+	if s.dead == 0 {
+		return
+	}
+	kept := s.items[:0]
+	for _, c := range s.items {
+		if c == nil {
+			continue
+		}
+		if st, ok := c.(*stick); ok {
+			st.slot = len(kept)
+		} else {
+			s.index[c] = len(kept)
+		}
+		kept = append(kept, c)
+	}
+	clear(s.items[len(kept):])
+	s.items = kept
+	s.dead = 0
+	s.layout++
+}
+
+// reset removes all the constraints.
+func (s *constraintStore) reset() {
+	*s = constraintStore{version: s.version + 1, layout: s.layout + 1}
+}
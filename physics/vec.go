@@ -0,0 +1,39 @@
+package physics
+
+import "math"
+
+// Vec2 is a two dimensional vector used for positions, velocities and forces.
+type Vec2 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Vec returns a new vector with the given components.
+func Vec(x, y float64) Vec2 {
+	return Vec2{X: x, Y: y}
+}
+
+// Add returns the sum of v and u.
+func (v Vec2) Add(u Vec2) Vec2 {
+	return Vec2{v.X + u.X, v.Y + u.Y}
+}
+
+// Sub returns the difference of v and u.
+func (v Vec2) Sub(u Vec2) Vec2 {
+	return Vec2{v.X - u.X, v.Y - u.Y}
+}
+
+// Mul returns v scaled by s.
+func (v Vec2) Mul(s float64) Vec2 {
+	return Vec2{v.X * s, v.Y * s}
+}
+
+// Dot returns the dot product of v and u.
+func (v Vec2) Dot(u Vec2) float64 {
+	return v.X*u.X + v.Y*u.Y
+}
+
+// Len returns the length of the vector.
+func (v Vec2) Len() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y)
+}
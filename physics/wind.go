@@ -0,0 +1,129 @@
+package physics
+
+import "math"
+
+// WindMode defines how the wind is applied on the cloth.
+type WindMode int
+
+const (
+	// WindPerParticle accelerates each particle with the wind, no matter how the cloth is oriented.
+	WindPerParticle WindMode = iota
+	// WindPerTriangle applies the wind on the cloth triangles proportionally to the width they
+	// are exposing to the wind, which means that a cloth folded along the wind catches less of it.
+	// The bodies without triangles are not affected in this mode.
+	WindPerTriangle
+)
+
+// turbulenceScale converts the world coordinates into the noise space,
+// defining the size of the turbulent swirls.
+const turbulenceScale = 0.01
+
+// Wind describes the wind blowing over the world.
+type Wind struct {
+	Direction     float64 // the direction the wind is blowing towards, in radians
+	Strength      float64 // the force of the wind on a particle of unit mass, zero means no wind
+	GustFrequency float64 // the number of gusts per second
+	GustStrength  float64 // the relative strength of the gusts in the [0, 1] range
+	Turbulence    float64 // the amplitude of the spatial turbulence in the [0, 1] range
+	Mode          WindMode
+}
+
+// At returns the wind force acting on a particle of unit mass at the pos position and t simulation time.
+func (w Wind) At(pos Vec2, t float64) Vec2 {
+	if w.Strength == 0 {
+		return Vec2{}
+	}
+	dir, strength := w.Direction, w.Strength
+
+	if w.GustFrequency > 0 {
+		// The gusts are the sum of two sine waves with unrelated frequencies,
+		// which makes them to not repeat in a perfectly regular way.
+		f := 2 * math.Pi * w.GustFrequency * t
+		gust := 0.5*math.Sin(f) + 0.5*math.Sin(1.7*f+1.3)
+		strength *= 1 + w.GustStrength*gust
+	}
+	if w.Turbulence > 0 {
+		// The noise is moving with the time, so the turbulence is changing smoothly.
+		x, y := pos.X*turbulenceScale, pos.Y*turbulenceScale
+		dir += w.Turbulence * math.Pi * 0.5 * noise(x+t, y)
+		strength *= 1 + w.Turbulence*noise(x, y-t+100)
+	}
+	return Vec(math.Cos(dir), math.Sin(dir)).Mul(strength)
+}
+
+// applyWind adds the acceleration caused by the wind to the cloth particles,
+// so the heavier particles are moved less by the same wind.
+func (c *Cloth) applyWind(wind Wind, t float64) {
+	if wind.Strength == 0 {
+		return
+	}
+
+	ps := &c.particles
+	switch wind.Mode {
+	case WindPerParticle:
+		for i, p := range ps.pos {
+			if ps.free(i) {
+				ps.acc[i] = ps.acc[i].Add(wind.At(p, t).Mul(ps.invMass[i]))
+			}
+		}
+	case WindPerTriangle:
+		for _, tri := range c.triangles {
+			if !ps.active(tri[0]) || !ps.active(tri[1]) || !ps.active(tri[2]) {
+				continue
+			}
+			a, b, d := ps.pos[tri[0]], ps.pos[tri[1]], ps.pos[tri[2]]
+			force := wind.At(a.Add(b).Add(d).Mul(1.0/3), t)
+			// The gusts and the turbulence can calm the wind down completely.
+			if force.Len() == 0 {
+				continue
+			}
+
+			// The exposed width of the triangle is its extent perpendicular to the wind, relative
+			// to its longest edge. A triangle stretched along the wind exposes almost nothing.
+			n := Vec(-force.Y, force.X).Mul(1 / force.Len())
+			pa, pb, pd := a.Dot(n), b.Dot(n), d.Dot(n)
+			width := max(pa, pb, pd) - min(pa, pb, pd)
+			edge := max(b.Sub(a).Len(), d.Sub(b).Len(), a.Sub(d).Len())
+			if edge == 0 {
+				continue
+			}
+			force = force.Mul(width / edge)
+
+			// Each particle gets the average of the wind caught by its triangles.
+			for _, i := range tri {
+				if !ps.pinned(i) {
+					ps.acc[i] = ps.acc[i].Add(force.Mul(ps.invMass[i] / float64(c.triangleCount[i])))
+				}
+			}
+		}
+	}
+}
+
+// noise returns a smooth pseudo random value noise in the [-1, 1] range.
+func noise(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := smoothstep(x-x0), smoothstep(y-y0)
+	ix, iy := int64(x0), int64(y0)
+
+	top := lerp(hash(ix, iy), hash(ix+1, iy), fx)
+	bottom := lerp(hash(ix, iy+1), hash(ix+1, iy+1), fx)
+
+	return lerp(top, bottom, fy)*2 - 1
+}
+
+// hash returns a pseudo random value in the [0, 1] range for the lattice point {x, y}.
+func hash(x, y int64) float64 {
+	h := uint64(x)*0x9e3779b97f4a7c15 ^ uint64(y)*0xc2b2ae3d27d4eb4f
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return float64(h>>11) / (1 << 53)
+}
+
+func smoothstep(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
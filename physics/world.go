@@ -0,0 +1,166 @@
+package physics
+
+// mouseForceRate is the dragging force gained per second while the left mouse button is held.
+const mouseForceRate = 5
+
+// Bounds defines the rectangular area the simulated bodies are confined to.
+// The top-left corner of the area is always at the origin. The behaviour of
+// each edge is defined separately, indexed by the Edge constants.
+type Bounds struct {
+	Width  float64
+	Height float64
+	Edges  [4]Boundary
+}
+
+// World is the headless simulation space, holding the simulated cloths together
+// with the bounds and the parameters they are simulated with.
+// It has no dependency on the windowing or the rendering layer.
+type World struct {
+	Bounds Bounds
+	Mouse  *Mouse
+	Wind   Wind
+
+	params     Params
+	integrator Integrator
+	forces     []Force
+	obstacles  []Obstacle
+	time       float64 // the simulation time elapsed since the world was created
+	cloths     []*Cloth
+	idle       Mouse
+
+	subscribers    []subscriber
+	lastSubscriber int
+	events         []Event // the events queued during the simulation step
+	stepping       bool
+
+	pool *workerPool // the goroutines of the parallel constraint solver
+}
+
+// NewWorld creates a new simulation world with the provided bounds and the default parameters.
+func NewWorld(width, height float64) *World {
+	return &World{
+		Bounds:     Bounds{Width: width, Height: height},
+		params:     DefaultParams(),
+		integrator: PositionVerlet{},
+	}
+}
+
+// Integrator returns the integrator the particles are advanced in time with.
+func (w *World) Integrator() Integrator {
+	return w.integrator
+}
+
+// SetIntegrator replaces the integrator the particles are advanced in time with.
+// It can be changed between the steps, a nil integrator restoring the position Verlet one.
+func (w *World) SetIntegrator(i Integrator) {
+	if i == nil {
+		i = PositionVerlet{}
+	}
+	w.integrator = i
+}
+
+// Params returns a copy of the parameters the world is simulated with.
+func (w *World) Params() Params {
+	return w.params
+}
+
+// SetParams validates and replaces the simulation parameters.
+// The parameters are left unchanged in case the validation fails.
+func (w *World) SetParams(p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	w.params = p
+	return nil
+}
+
+// Add adds a new cloth to the world.
+func (w *World) Add(c *Cloth) {
+	c.world = w
+	w.cloths = append(w.cloths, c)
+}
+
+// Cloths returns the cloths simulated in the world.
+func (w *World) Cloths() []*Cloth {
+	return w.cloths
+}
+
+// AddForce registers a new force field acting on the world's particles.
+func (w *World) AddForce(f Force) {
+	w.forces = append(w.forces, f)
+}
+
+// RemoveForce removes a force field from the world.
+func (w *World) RemoveForce(f Force) {
+	for i, force := range w.forces {
+		if force == f {
+			w.forces = append(w.forces[:i], w.forces[i+1:]...)
+			break
+		}
+	}
+}
+
+// ClearForces removes all the force fields from the world.
+func (w *World) ClearForces() {
+	w.forces = nil
+}
+
+// Forces returns the force fields registered in the world.
+func (w *World) Forces() []Force {
+	return w.forces
+}
+
+// AddObstacle adds a new static obstacle the cloths are colliding with.
+func (w *World) AddObstacle(o Obstacle) {
+	w.obstacles = append(w.obstacles, o)
+}
+
+// ClearObstacles removes all the obstacles from the world.
+func (w *World) ClearObstacles() {
+	w.obstacles = nil
+}
+
+// Obstacles returns the obstacles of the world.
+func (w *World) Obstacles() []Obstacle {
+	return w.obstacles
+}
+
+// Resize updates the size of the world bounds, keeping the behaviour of the edges.
+func (w *World) Resize(width, height float64) {
+	w.Bounds.Width, w.Bounds.Height = width, height
+}
+
+// Step advances the simulation by dt. In case the world has no mouse attached,
+// the particles are updated as if the mouse would be idle.
+func (w *World) Step(dt float64) {
+	mouse := w.Mouse
+	if mouse == nil {
+		mouse = &w.idle
+	}
+	// The dragging force is increased with the simulation time and not with the
+	// wall clock, so that it doesn't depend on the frame rate either.
+	if mouse.GetLeftButton() {
+		mouse.SetForce(mouse.GetForce() + dt*mouseForceRate)
+	}
+	w.stepping = true
+	for _, c := range w.cloths {
+		c.update(w, mouse, dt)
+	}
+	w.stepping = false
+	w.time += dt
+
+	w.flush()
+
+	// The cloths are compacted only after the events of the step have been delivered,
+	// since the compaction moves the particles referenced by the events to new indices.
+	for _, c := range w.cloths {
+		if c.CompactInterval > 0 && c.steps%c.CompactInterval == 0 {
+			c.Compact()
+		}
+	}
+}
+
+// Time returns the simulation time elapsed since the world was created.
+func (w *World) Time() float64 {
+	return w.time
+}
@@ -0,0 +1,53 @@
+package physics
+
+import (
+	"fmt"
+	"image/color"
+	"testing"
+)
+
+// BenchmarkStep measures a single simulation step of a 1024x400 cloth
+// on two lattice spacings with the following parameter sets:
+//
+//   - default: the default parameters,
+//   - stiff: 4 iterations with shear and bend stiffness 0.5,
+//   - fields: turbulent wind, a vortex and 4 substeps.
+func BenchmarkStep(b *testing.B) {
+	setups := []struct {
+		name  string
+		setup func(w *World, p *Params)
+	}{
+		{"default", func(w *World, p *Params) {}},
+		{"stiff", func(w *World, p *Params) {
+			p.Iterations = 4
+			p.ShearStiffness = 0.5
+			p.BendStiffness = 0.5
+		}},
+		{"fields", func(w *World, p *Params) {
+			p.Substeps = 4
+			w.Wind = Wind{Strength: 300, Turbulence: 1}
+			w.AddForce(NewVortex(Vec(640, 300), 1500, 150))
+		}},
+	}
+	for _, spacing := range []int{6, 2} {
+		for _, s := range setups {
+			b.Run(fmt.Sprintf("spacing%d/%s", spacing, s.name), func(b *testing.B) {
+				c := NewCloth(1024, 400, spacing, color.NRGBA{})
+				c.Init(128, 100)
+
+				w := NewWorld(1280, 820)
+				p := DefaultParams()
+				s.setup(w, &p)
+				if err := w.SetParams(p); err != nil {
+					b.Fatal(err)
+				}
+				w.Add(c)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					w.Step(0.022)
+				}
+			})
+		}
+	}
+}
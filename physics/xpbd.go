@@ -0,0 +1,68 @@
+package physics
+
+// Solver selects the method the constraints are solved with.
+type Solver int
+
+const (
+	// SolverRelaxation moves the particles by a fixed fraction of the constraint error
+	// on each relaxation pass, which means that the stiffness of the cloth depends
+	// on the number of iterations and substeps.
+	SolverRelaxation Solver = iota
+	// SolverXPBD solves the constraints with the Extended Position Based Dynamics method,
+	// where the stiffness of the constraints is defined by their compliance, the inverse
+	// of the stiffness. The behaviour of the cloth is the same, whatever the number of
+	// iterations and substeps is, the more of them only making the solution more accurate.
+	SolverXPBD
+)
+
+// String returns the human readable name of the solver.
+func (s Solver) String() string {
+	switch s {
+	case SolverRelaxation:
+		return "Relaxation"
+	case SolverXPBD:
+		return "XPBD"
+	default:
+		return "Unknown"
+	}
+}
+
+// multiplier is implemented by the constraints accumulating a Lagrange multiplier
+// in the XPBD mode, which has to be reset at the beginning of each substep.
+type multiplier interface {
+	resetLambda()
+}
+
+// resetLambdas resets the Lagrange multipliers of the cloth constraints.
+func (c *Cloth) resetLambdas() {
+	for _, ct := range c.constraints.items {
+		if m, ok := ct.(multiplier); ok {
+			m.resetLambda()
+		}
+	}
+}
+
+// solveCompliant moves the particles a and b towards the distance defined by length using
+// the XPBD method, accumulating the Lagrange multiplier of the constraint into lambda.
+// With maxOnly set, the constraint is solved only when the particles are farther away.
+func solveCompliant(ps Particles, a, b int, length, compliance float64, lambda *float64, dt float64, maxOnly bool) {
+	delta := ps.Position(a).Sub(ps.Position(b))
+	dist := delta.Len()
+	if dist == 0 || maxOnly && dist <= length {
+		return
+	}
+	wa, wb := ps.InvMass(a), ps.InvMass(b)
+
+	// The compliance is scaled by the substep duration, which
+	// makes the stiffness independent of the time step.
+	alpha := compliance / (dt * dt)
+	if wa+wb+alpha == 0 {
+		return
+	}
+	dl := (length - dist - alpha**lambda) / (wa + wb + alpha)
+	*lambda += dl
+
+	n := delta.Mul(dl / dist)
+	ps.s.pos[a] = ps.s.pos[a].Add(n.Mul(wa))
+	ps.s.pos[b] = ps.s.pos[b].Sub(n.Mul(wb))
+}
@@ -0,0 +1,58 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+
+	"github.com/esimov/cloth-physics/physics"
+)
+
+var (
+	attractorColor = color.NRGBA{R: 0x2e, G: 0x8b, B: 0x57, A: 0x90}
+	repulsorColor  = color.NRGBA{R: 0xd9, G: 0x03, B: 0x68, A: 0x90}
+	vortexColor    = color.NRGBA{R: 0x1e, G: 0x66, B: 0xd0, A: 0x90}
+)
+
+// Forces draws the center and the area of influence of the built-in force fields.
+// The custom force fields, and the ones without a limited extent, are not drawn.
+func Forces(gtx layout.Context, forces []physics.Force) {
+	for _, f := range forces {
+		switch f := f.(type) {
+		case *physics.PointForce:
+			col := attractorColor
+			if f.Strength < 0 {
+				col = repulsorColor
+			}
+			drawField(gtx, f.Center, f.Radius, col)
+		case *physics.VortexForce:
+			drawField(gtx, f.Center, f.Radius, vortexColor)
+		case *physics.DirectionalForce:
+			if f.Radius > 0 {
+				drawField(gtx, f.Center, f.Radius, attractorColor)
+			}
+		}
+	}
+}
+
+// drawField draws a small dot in the center of the force field and a ring around its area.
+func drawField(gtx layout.Context, center physics.Vec2, radius float64, col color.NRGBA) {
+	paint.FillShape(gtx.Ops, col, clip.Ellipse(circle(center, 3)).Op(gtx.Ops))
+	if radius > 0 {
+		paint.FillShape(gtx.Ops, col, clip.Stroke{
+			Path:  clip.Ellipse(circle(center, radius)).Path(gtx.Ops),
+			Width: 1,
+		}.Op())
+	}
+}
+
+// circle returns the bounding box of the circle with the provided center and radius.
+func circle(center physics.Vec2, radius float64) image.Rectangle {
+	return image.Rect(
+		int(center.X-radius), int(center.Y-radius),
+		int(center.X+radius), int(center.Y+radius),
+	)
+}
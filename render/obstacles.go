@@ -0,0 +1,69 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+
+	"github.com/esimov/cloth-physics/physics"
+)
+
+var obstacleColor = color.NRGBA{R: 0x5a, G: 0x5a, B: 0x5a, A: 0xff}
+
+// capsuleSegments is the number of segments used to approximate the rounded ends of a capsule.
+const capsuleSegments = 16
+
+// Obstacles draws the static obstacles of the world.
+func Obstacles(gtx layout.Context, obstacles []physics.Obstacle) {
+	for _, o := range obstacles {
+		switch s := o.Shape.(type) {
+		case physics.Circle:
+			paint.FillShape(gtx.Ops, obstacleColor, clip.Ellipse(circle(s.Center, s.Radius)).Op(gtx.Ops))
+		case physics.Box:
+			rect := image.Rect(int(s.Min.X), int(s.Min.Y), int(s.Max.X), int(s.Max.Y))
+			paint.FillShape(gtx.Ops, obstacleColor, clip.Rect(rect).Op())
+		case physics.Capsule:
+			drawPolygon(gtx, capsuleOutline(s))
+		case physics.Polygon:
+			drawPolygon(gtx, s.Points)
+		}
+	}
+}
+
+// drawPolygon fills the polygon defined by the points.
+func drawPolygon(gtx layout.Context, points []physics.Vec2) {
+	if len(points) < 3 {
+		return
+	}
+	var path clip.Path
+	path.Begin(gtx.Ops)
+	path.MoveTo(point(points[0]))
+	for _, p := range points[1:] {
+		path.LineTo(point(p))
+	}
+	path.Close()
+	paint.FillShape(gtx.Ops, obstacleColor, clip.Outline{Path: path.End()}.Op())
+}
+
+// capsuleOutline returns the outline of the capsule, with its rounded ends approximated by segments.
+func capsuleOutline(c physics.Capsule) []physics.Vec2 {
+	angle := math.Atan2(c.B.Y-c.A.Y, c.B.X-c.A.X)
+	points := make([]physics.Vec2, 0, 2*(capsuleSegments+1))
+
+	// The half circle around B goes from the right side of the segment to the left one,
+	// and the half circle around A continues from the left side back to the right one.
+	for _, end := range [2]struct {
+		center physics.Vec2
+		start  float64
+	}{{c.B, angle - math.Pi/2}, {c.A, angle + math.Pi/2}} {
+		for i := 0; i <= capsuleSegments; i++ {
+			a := end.start + math.Pi*float64(i)/capsuleSegments
+			points = append(points, physics.Vec(end.center.X+c.Radius*math.Cos(a), end.center.Y+c.Radius*math.Sin(a)))
+		}
+	}
+	return points
+}